@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// alignFASTQ assigns reads of a FASTQ file to amplicons and writes the alignments to a SAM file,
+// which is then typed in the same way as an external alignment. Given the FASTQ of read 2, read pairs are merged
+// before alignment, and the merge counts are returned.
+func alignFASTQ(index *typing.AmpliconIndex, fastq, fastq2 io.Reader, path string) (stat *typing.MergeStat) {
+	handle, err := os.Create(path)
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("@HD\tVN:1.6\tSO:unsorted\n")
+	check(err)
+	for _, amplicon := range index.Amplicons {
+		_, err = writer.WriteString(fmt.Sprintf("@CO\tamplicon %s %s:%d-%d\n",
+			amplicon.Name, amplicon.CHROM, amplicon.Start, amplicon.Start+int64(len(amplicon.Seq))-1))
+		check(err)
+	}
+	_, err = writer.WriteString("@PG\tID:TypingMarkers\tPN:TypingMarkers\tVN:" + VERSION + "\n")
+	check(err)
+
+	stat, err = typing.AlignFASTQ(index, fastq, fastq2, writer,
+		typing.MergeOptions{MinOverlap: *minOverlap, MaxMismatch: *maxOverlapMismatch})
+	check(err)
+	check(writer.Flush())
+	return
+}
+
+// writeAmpliconStat writes the number of reads assigned to each amplicon to a .amplicon.tab file.
+func writeAmpliconStat(index *typing.AmpliconIndex) {
+	handle, err := os.Create(*OUT + ".amplicon.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Amplicon\tCHROM\tStart\tEnd\tReads\n")
+	check(err)
+	for i, amplicon := range index.Amplicons {
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%s\t%d\t%d\t%d\n", amplicon.Name, amplicon.CHROM,
+			amplicon.Start, amplicon.Start+int64(len(amplicon.Seq))-1, index.Assigned[i]))
+		check(err)
+	}
+	_, err = writer.WriteString(fmt.Sprintf("*\t*\t0\t0\t%d\n", index.Unassigned))
+	check(err)
+	check(writer.Flush())
+}
+
+// typeFASTQ aligns reads given by -FASTQ to amplicons given by -AMPLICON, or built from -FASTA around the markers,
+// and writes them to the SAM file given by -SAM. Read pairs are merged first with -FASTQ2.
+func typeFASTQ(markers []typing.GeneticMarker, reference *typing.FASTA) {
+	var amplicons []typing.Amplicon
+	switch {
+	case *ampliconPath != "":
+		handle, err := os.Open(*ampliconPath)
+		check(err)
+		amplicons, err = typing.NewAmplicons(handle)
+		check(err)
+		check(handle.Close())
+	case reference != nil:
+		amplicons = typing.BuildAmplicons(markers, reference, *ampliconFlank)
+	default:
+		log.Panic("-FASTQ requires either -AMPLICON or -FASTA")
+	}
+
+	index := typing.NewAmpliconIndex(amplicons)
+	handle, err := os.Open(*FASTQPath)
+	check(err)
+	if *FASTQ2Path == "" {
+		alignFASTQ(index, handle, nil, *SAMPath)
+		check(handle.Close())
+		writeAmpliconStat(index)
+		return
+	}
+	handle2, err := os.Open(*FASTQ2Path)
+	check(err)
+	stat := alignFASTQ(index, handle, handle2, *SAMPath)
+	check(handle.Close())
+	check(handle2.Close())
+	writeAmpliconStat(index)
+	writeMergeStat(index, stat)
+}
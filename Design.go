@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"TypingMarkers/typing"
+)
+
+// PhasedSNP holds a bi- or multi-allelic SNP and the phased genotypes of all samples in a population VCF.
+type PhasedSNP struct {
+	CHROM   string
+	POS     int64
+	ID      string
+	Alleles []typing.BASE // REF followed by ALTs.
+
+	// Haplotypes holds allele indexes of both chromosomes of each sample, -1 for missing or unphased genotype.
+	Haplotypes [][2]int
+}
+
+// CandidateMH is a cluster of SNPs within a window that might be designed as a microhaplotype.
+type CandidateMH struct {
+	SNPs      []PhasedSNP
+	Frequency map[typing.AlleleMH]float64
+	Ae        float64
+	PIC       float64
+}
+
+// parseGT parses a phased genotype (e.g. 0|1) into allele indexes.
+func parseGT(s string) [2]int {
+	var gt = [2]int{-1, -1}
+	// Unphased genotypes can not build haplotypes.
+	parts := strings.Split(strings.Split(s, ":")[0], "|")
+	if len(parts) != 2 {
+		return gt
+	}
+	for i, part := range parts {
+		if n, err := strconv.Atoi(part); err == nil {
+			gt[i] = n
+		}
+	}
+	if gt[0] == -1 || gt[1] == -1 {
+		return [2]int{-1, -1}
+	}
+	return gt
+}
+
+// NewPhasedSNPs imports SNPs with phased genotypes from a multi-sample VCF. Sites whose alleles are not single base are skipped.
+func NewPhasedSNPs(file *os.File) (snps []PhasedSNP) {
+	scanner := typing.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 10 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pos, err := strconv.ParseInt(fields[1], 10, 64)
+		check(err)
+		var alleles = []typing.BASE{fields[3]}
+		alleles = append(alleles, strings.Split(fields[4], ",")...)
+		var snv = true
+		for _, allele := range alleles {
+			snv = snv && len(allele) == 1
+		}
+		if !snv {
+			continue
+		}
+		// GT is the first key of FORMAT by VCF specification.
+		if !strings.HasPrefix(fields[8], "GT") {
+			continue
+		}
+		snp := PhasedSNP{CHROM: fields[0], POS: pos, ID: fields[2], Alleles: alleles}
+		for _, sample := range fields[9:] {
+			snp.Haplotypes = append(snp.Haplotypes, parseGT(sample))
+		}
+		snps = append(snps, snp)
+	}
+	check(scanner.Err())
+	return
+}
+
+// NewCandidateMH estimates haplotype frequencies of a SNP cluster from samples phased at all sites.
+func NewCandidateMH(snps []PhasedSNP) CandidateMH {
+	var (
+		candidate = CandidateMH{SNPs: snps, Frequency: make(map[typing.AlleleMH]float64)}
+		total     float64
+	)
+	for sample := range snps[0].Haplotypes {
+		for chromosome := 0; chromosome < 2; chromosome++ {
+			var haplotype []string
+			for _, snp := range snps {
+				if i := snp.Haplotypes[sample][chromosome]; i >= 0 && i < len(snp.Alleles) {
+					haplotype = append(haplotype, snp.Alleles[i])
+				}
+			}
+			if len(haplotype) != len(snps) {
+				continue
+			}
+			candidate.Frequency[strings.Join(haplotype, "-")]++
+			total++
+		}
+	}
+	var freq []float64
+	for allele := range candidate.Frequency {
+		candidate.Frequency[allele] /= total
+		freq = append(freq, candidate.Frequency[allele])
+	}
+	candidate.Ae = typing.EffectiveAlleles(freq)
+	candidate.PIC = typing.PIC(freq)
+	return candidate
+}
+
+// ClusterSNPs builds a candidate from every SNP together with the following SNPs within window bp,
+// having between minSNPs and maxSNPs sites. Of several records at the same position, e.g. a split multi-allelic
+// site, only the first is kept, as a microhaplotype has one base per position.
+func ClusterSNPs(snps []PhasedSNP, window int64, minSNPs, maxSNPs int) (candidates []CandidateMH) {
+	sort.SliceStable(snps, func(i, j int) bool {
+		if snps[i].CHROM == snps[j].CHROM {
+			return snps[i].POS < snps[j].POS
+		}
+		return snps[i].CHROM < snps[j].CHROM
+	})
+	var unique []PhasedSNP
+	for i, snp := range snps {
+		if i > 0 && snp.CHROM == snps[i-1].CHROM && snp.POS == snps[i-1].POS {
+			continue
+		}
+		unique = append(unique, snp)
+	}
+	snps = unique
+	for i := range snps {
+		var j = i + 1
+		for j < len(snps) && j-i < maxSNPs && snps[j].CHROM == snps[i].CHROM && snps[j].POS-snps[i].POS <= window {
+			j++
+		}
+		if j-i >= minSNPs {
+			candidates = append(candidates, NewCandidateMH(snps[i:j]))
+		}
+	}
+	return
+}
+
+// SelectCandidates ranks candidates by Ae then PIC, and retains the best non-overlapping ones.
+func SelectCandidates(candidates []CandidateMH, minAe float64, top int) (selected []CandidateMH) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Ae == candidates[j].Ae {
+			return candidates[i].PIC > candidates[j].PIC
+		}
+		return candidates[i].Ae > candidates[j].Ae
+	})
+	for _, c := range candidates {
+		if c.Ae < minAe || (top > 0 && len(selected) == top) {
+			break
+		}
+		var overlap bool
+		for _, s := range selected {
+			overlap = overlap || (c.SNPs[0].CHROM == s.SNPs[0].CHROM &&
+				c.SNPs[0].POS <= s.SNPs[len(s.SNPs)-1].POS && s.SNPs[0].POS <= c.SNPs[len(c.SNPs)-1].POS)
+		}
+		if !overlap {
+			selected = append(selected, c)
+		}
+	}
+	return
+}
+
+// VCFString converts the candidate to a microhaplotype record in the panel VCF format with OFFSET.
+func (c CandidateMH) VCFString(id string) string {
+	var (
+		first  = c.SNPs[0]
+		ref    []string
+		offset []string
+		alt    []typing.AlleleMH
+		af     []string
+	)
+	for _, snp := range c.SNPs {
+		ref = append(ref, snp.Alleles[0])
+		if snp.POS != first.POS {
+			offset = append(offset, strconv.FormatInt(snp.POS-first.POS, 10))
+		}
+	}
+	REF := strings.Join(ref, "-")
+	for allele := range c.Frequency {
+		if allele != REF {
+			alt = append(alt, allele)
+		}
+	}
+	sort.Slice(alt, func(i, j int) bool {
+		if c.Frequency[alt[i]] == c.Frequency[alt[j]] {
+			return alt[i] < alt[j]
+		}
+		return c.Frequency[alt[i]] > c.Frequency[alt[j]]
+	})
+	for _, allele := range alt {
+		af = append(af, fmt.Sprintf("%0.4f", c.Frequency[allele]))
+	}
+	if len(alt) == 0 {
+		alt, af = []typing.AlleleMH{"."}, []string{"0"}
+	}
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t60\tPASS\tAF=%s;OFFSET=%s;AE=%0.4f;PIC=%0.4f",
+		first.CHROM, first.POS, id, REF, strings.Join(alt, ","),
+		strings.Join(af, ","), strings.Join(offset, ","), c.Ae, c.PIC)
+}
+
+// design is the sub-command discovering microhaplotypes from a population SNP VCF with phased genotypes.
+func design(args []string) {
+	var (
+		flags   = flag.NewFlagSet("design", flag.ExitOnError)
+		out     = flags.String("OUT", "design", "specify the prefix of all output files")
+		vcfPath = flags.String("VCF", "", "specify population SNP VCF path with phased genotypes")
+		window  = flags.Int64("window", 200, "specify maximum span (bp) of a microhaplotype")
+		minSNPs = flags.Int("min_snps", 2, "specify minimum number of SNPs in a microhaplotype")
+		maxSNPs = flags.Int("max_snps", 8, "specify maximum number of SNPs in a microhaplotype")
+		minAe   = flags.Float64("min_ae", 2, "specify minimum effective number of alleles of a microhaplotype")
+		top     = flags.Int("top", 0, "specify number of selected microhaplotypes, 0 for all")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: TypingMarkers design [options] -VCF population.vcf")
+		flags.PrintDefaults()
+	}
+	check(flags.Parse(args))
+	if *vcfPath == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	handle, err := os.Open(*vcfPath)
+	check(err)
+	snps := NewPhasedSNPs(handle)
+	check(handle.Close())
+
+	selected := SelectCandidates(ClusterSNPs(snps, *window, *minSNPs, *maxSNPs), *minAe, *top)
+
+	vcfHandle, err := os.Create(*out + ".vcf")
+	check(err)
+	tabHandle, err := os.Create(*out + ".design.tab")
+	check(err)
+	vcfWriter, tabWriter := bufio.NewWriter(vcfHandle), bufio.NewWriter(tabHandle)
+	_, err = vcfWriter.WriteString(fmt.Sprintf(
+		"##fileformat=VCFv4.2\n##source=TypingMarkers %s\n##designed_by=design -VCF %s\n", VERSION, *vcfPath))
+	check(err)
+	_, err = tabWriter.WriteString("#Marker\tCHROM\tStart\tEnd\tSNPs\tAlleles\tAe\tPIC\n")
+	check(err)
+
+	var counter = make(map[string]int)
+	for _, c := range selected {
+		first, last := c.SNPs[0], c.SNPs[len(c.SNPs)-1]
+		counter[first.CHROM]++
+		id := fmt.Sprintf("mh%s-%03d", first.CHROM, counter[first.CHROM])
+		_, err = vcfWriter.WriteString(c.VCFString(id) + "\n")
+		check(err)
+		_, err = tabWriter.WriteString(fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%d\t%0.4f\t%0.4f\n",
+			id, first.CHROM, first.POS, last.POS, len(c.SNPs), len(c.Frequency), c.Ae, c.PIC))
+		check(err)
+	}
+	check(vcfWriter.Flush())
+	check(tabWriter.Flush())
+	check(vcfHandle.Close())
+	check(tabHandle.Close())
+	fmt.Printf("%d microhaplotypes were designed from %d SNPs.\n", len(selected), len(snps))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"TypingMarkers/typing"
+)
+
+func TestClusterSNPs(t *testing.T) {
+	var snps = []PhasedSNP{
+		{CHROM: "chr2", POS: 10, Alleles: []typing.BASE{"T", "C"}, Haplotypes: [][2]int{{0, 1}, {0, 0}}},
+		{CHROM: "chr1", POS: 400, Alleles: []typing.BASE{"G", "A"}, Haplotypes: [][2]int{{0, 0}, {0, 1}}},
+		{CHROM: "chr1", POS: 150, Alleles: []typing.BASE{"C", "T"}, Haplotypes: [][2]int{{0, 1}, {1, 0}}},
+		{CHROM: "chr1", POS: 100, Alleles: []typing.BASE{"A", "G"}, Haplotypes: [][2]int{{0, 1}, {0, 1}}},
+		// A split multi-allelic site at the same position is not a second SNP.
+		{CHROM: "chr1", POS: 100, Alleles: []typing.BASE{"A", "T"}, Haplotypes: [][2]int{{0, 0}, {1, 0}}},
+	}
+	candidates := ClusterSNPs(snps, 100, 2, 8)
+	if len(candidates) != 1 || len(candidates[0].SNPs) != 2 {
+		t.Fatalf("clustered %v, want SNPs at 100 and 150 of chr1", candidates)
+	}
+	c := candidates[0]
+	if c.SNPs[0].Alleles[1] != "G" || c.SNPs[1].POS != 150 {
+		t.Errorf("clustered SNPs %v, want the first record at 100", c.SNPs)
+	}
+	// Four haplotypes A-C, G-T, A-T and G-C at 0.25.
+	if len(c.Frequency) != 4 || c.Ae != 4 || math.Abs(c.PIC-0.703125) > 1e-9 {
+		t.Errorf("frequencies %v with Ae %g and PIC %g, want 4 alleles at 0.25", c.Frequency, c.Ae, c.PIC)
+	}
+
+	const want = "chr1\t100\tmh1\tA-C\tA-T,G-C,G-T\t60\tPASS\tAF=0.2500,0.2500,0.2500;OFFSET=50;AE=4.0000;PIC=0.7031"
+	if got := c.VCFString("mh1"); got != want {
+		t.Errorf("VCFString() = %q, want %q", got, want)
+	}
+	if _, err := typing.NewVCFFormat(strings.NewReader(c.VCFString("mh1") + "\n")); err != nil {
+		t.Errorf("VCFString() is not a panel record: %v", err)
+	}
+}
+
+func TestSelectCandidates(t *testing.T) {
+	var candidate = func(chrom string, start, end int64, ae, pic float64) CandidateMH {
+		return CandidateMH{SNPs: []PhasedSNP{{CHROM: chrom, POS: start}, {CHROM: chrom, POS: end}}, Ae: ae, PIC: pic}
+	}
+	var candidates = []CandidateMH{
+		candidate("chr1", 140, 200, 3, 0.6), // overlaps the best one.
+		candidate("chr2", 10, 20, 2.5, 0.5),
+		candidate("chr1", 100, 150, 4, 0.7),
+		candidate("chr2", 300, 320, 2.5, 0.55), // ranked before the equal Ae by PIC.
+		candidate("chr3", 10, 20, 1.5, 0.3),
+	}
+	selected := SelectCandidates(candidates, 2, 0)
+	if len(selected) != 3 || selected[0].SNPs[0].POS != 100 || selected[1].SNPs[0].POS != 300 || selected[2].SNPs[0].POS != 10 {
+		t.Errorf("selected %v, want chr1:100, chr2:300 and chr2:10", selected)
+	}
+	if selected = SelectCandidates(candidates, 2, 1); len(selected) != 1 || selected[0].Ae != 4 {
+		t.Errorf("selected %v, want the best candidate only", selected)
+	}
+}
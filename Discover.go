@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"TypingMarkers/typing"
+)
+
+// NovelAllele collects the evidence of an unknown complete haplotype across samples.
+type NovelAllele struct {
+	Marker  string
+	Allele  typing.AlleleMH
+	Samples []string
+	Depth   float64
+	MaxFrac float64
+}
+
+// parseAlleleDepth parses the "allele:depth allele:depth " field written by mapToString.
+func parseAlleleDepth(s string) map[typing.AlleleMH]float64 {
+	var alleles = make(map[typing.AlleleMH]float64)
+	for _, item := range strings.Fields(s) {
+		i := strings.LastIndex(item, ":")
+		if i == -1 {
+			continue
+		}
+		n, err := strconv.ParseFloat(item[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		alleles[item[:i]] += n
+	}
+	return alleles
+}
+
+// sampleName derives sample name from the path of a verbose output.
+func sampleName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".verbose.csv")
+}
+
+// CollectRareAlleles aggregates RareAlleles of microhaplotypes from verbose outputs of several samples.
+// A rare allele is counted as evidence in a sample when its depth and fraction of the marker depth reach the thresholds.
+// Depths are the EM-expected reads, written rounded to whole reads in verbose outputs, and minDepth compares to them.
+func CollectRareAlleles(panel map[string]typing.MH, verbose []string, minDepth, minFrac float64) map[string]map[typing.AlleleMH]*NovelAllele {
+	var novel = make(map[string]map[typing.AlleleMH]*NovelAllele)
+	for _, path := range verbose {
+		handle, err := os.Open(path)
+		check(err)
+		scanner := bufio.NewScanner(handle)
+		for scanner.Scan() {
+			fields := strings.Split(scanner.Text(), "\t")
+			if len(fields) < 5 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			mh, ok := panel[fields[2]]
+			if !ok {
+				continue
+			}
+			var (
+				alleles = parseAlleleDepth(fields[3])
+				rare    = parseAlleleDepth(fields[4])
+				depth   float64
+			)
+			for _, n := range alleles {
+				depth += n
+			}
+			for _, n := range rare {
+				depth += n
+			}
+			for allele, n := range rare {
+				// Only complete haplotypes with the same number of SNPs as the marker are proposed.
+				if len(allele) != 2*len(mh.OffSet)+1 || strings.Contains(allele, ".") {
+					continue
+				}
+				if n < minDepth || n/depth < minFrac {
+					continue
+				}
+				if _, ok := novel[mh.ID]; !ok {
+					novel[mh.ID] = make(map[typing.AlleleMH]*NovelAllele)
+				}
+				record, ok := novel[mh.ID][allele]
+				if !ok {
+					record = &NovelAllele{Marker: mh.ID, Allele: allele}
+					novel[mh.ID][allele] = record
+				}
+				record.Samples = append(record.Samples, sampleName(path))
+				record.Depth += n
+				if n/depth > record.MaxFrac {
+					record.MaxFrac = n / depth
+				}
+			}
+		}
+		check(scanner.Err())
+		check(handle.Close())
+	}
+	return novel
+}
+
+// ProposeAlleles retains the novel alleles supported by at least minSamples samples, sorted by marker and allele.
+func ProposeAlleles(novel map[string]map[typing.AlleleMH]*NovelAllele, minSamples int) (proposed []NovelAllele) {
+	for _, alleles := range novel {
+		for _, record := range alleles {
+			if len(record.Samples) >= minSamples {
+				proposed = append(proposed, *record)
+			}
+		}
+	}
+	sort.Slice(proposed, func(i, j int) bool {
+		if proposed[i].Marker == proposed[j].Marker {
+			return proposed[i].Allele < proposed[j].Allele
+		}
+		return proposed[i].Marker < proposed[j].Marker
+	})
+	return
+}
+
+// writeDiscoveredVCF copies the panel VCF, appending proposed alleles to ALT and their provenance to INFO NOVEL key.
+func writeDiscoveredVCF(panelPath, outPath string, proposed []NovelAllele, samples []string) {
+	var byMarker = make(map[string][]NovelAllele)
+	for _, p := range proposed {
+		byMarker[p.Marker] = append(byMarker[p.Marker], p)
+	}
+
+	in, err := os.Open(panelPath)
+	check(err)
+	defer in.Close()
+	out, err := os.Create(outPath)
+	check(err)
+	defer func() {
+		check(out.Close())
+	}()
+
+	var (
+		writer  = bufio.NewWriter(out)
+		scanner = bufio.NewScanner(in)
+		meta    = true
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// The provenance is recorded after the meta lines of the panel, which begin with ##fileformat.
+		if meta && !strings.HasPrefix(line, "##") {
+			meta = false
+			_, err = writer.WriteString(fmt.Sprintf("##discover=%s %s samples=%s\n",
+				VERSION, time.Now().Format("2006-01-02"), strings.Join(samples, ",")))
+			check(err)
+			_, err = writer.WriteString("##INFO=<ID=NOVEL,Number=.,Type=String,Description=\"Discovered allele:number of samples:depth\">\n")
+			check(err)
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 8 && !strings.HasPrefix(fields[0], "#") {
+			if alleles, ok := byMarker[fields[2]]; ok {
+				var alt, provenance []string
+				for _, a := range alleles {
+					alt = append(alt, a.Allele)
+					provenance = append(provenance, fmt.Sprintf("%s:%d:%0.f", a.Allele, len(a.Samples), a.Depth))
+				}
+				if fields[4] != "." {
+					alt = append([]string{fields[4]}, alt...)
+				}
+				fields[4] = strings.Join(alt, ",")
+				fields[7] = fields[7] + ";NOVEL=" + strings.Join(provenance, ",")
+				line = strings.Join(fields, "\t")
+			}
+		}
+		_, err = writer.WriteString(line + "\n")
+		check(err)
+	}
+	check(scanner.Err())
+	check(writer.Flush())
+}
+
+// discover is the sub-command proposing new ALT alleles from RareAlleles observed in a batch of samples.
+func discover(args []string) {
+	var (
+		flags      = flag.NewFlagSet("discover", flag.ExitOnError)
+		out        = flags.String("OUT", "discover", "specify the prefix of all output files")
+		panelPath  = flags.String("VCF", "", "specify panel VCF path")
+		minDepth   = flags.Float64("min_depth", 10, "specify minimum depth of a rare allele in one sample, in EM-expected reads")
+		minFrac    = flags.Float64("min_frac", 0.2, "specify minimum fraction of a rare allele in the marker depth of one sample")
+		minSamples = flags.Int("min_samples", 2, "specify minimum number of samples supporting a novel allele")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: TypingMarkers discover [options] sample1.verbose.csv sample2.verbose.csv ...")
+		flags.PrintDefaults()
+	}
+	check(flags.Parse(args))
+	if *panelPath == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	handle, err := os.Open(*panelPath)
+	check(err)
+	var panel = make(map[string]typing.MH)
+	markers, err := typing.NewVCFFormat(handle)
+	check(err)
+	for _, marker := range markers {
+		if mh, ok := marker.(typing.MH); ok {
+			panel[mh.ID] = mh
+		}
+	}
+	check(handle.Close())
+
+	var samples []string
+	for _, path := range flags.Args() {
+		samples = append(samples, sampleName(path))
+	}
+	proposed := ProposeAlleles(CollectRareAlleles(panel, flags.Args(), *minDepth, *minFrac), *minSamples)
+
+	tab, err := os.Create(*out + ".novel.tab")
+	check(err)
+	writer := bufio.NewWriter(tab)
+	_, err = writer.WriteString("#Marker\tAllele\tSamples\tDepth\tMaxFrac\tSupport\n")
+	check(err)
+	for _, p := range proposed {
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%s\t%d\t%0.f\t%0.4f\t%s\n",
+			p.Marker, p.Allele, len(p.Samples), p.Depth, p.MaxFrac, strings.Join(p.Samples, ",")))
+		check(err)
+	}
+	check(writer.Flush())
+	check(tab.Close())
+
+	writeDiscoveredVCF(*panelPath, *out+".vcf", proposed, samples)
+	fmt.Printf("%d novel alleles were proposed from %d samples.\n", len(proposed), len(samples))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"TypingMarkers/typing"
+)
+
+func TestCollectRareAlleles(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		panel = map[string]typing.MH{
+			"mh": typing.NewMH(typing.VCFFormat{CHROM: "chr1", POS: 10, ID: "mh", REF: "C-T", ALT: "A-G"}, []uint64{5}),
+			// REF left to be filled from -FASTA.
+			"dot": typing.NewMH(typing.VCFFormat{CHROM: "chr1", POS: 50, ID: "dot", REF: ".", ALT: "A-G"}, []uint64{5}),
+		}
+		samples = map[string]string{
+			// C-G at 13 of 118 expected reads, a partial allele, and T-T below -min_depth.
+			"a.verbose.csv": "#header\nchr1\t10\tmh\tC-T:50 A-G:31 \tC-.:20 C-G:13 T-T:4 \t\t\n" +
+				"chr1\t90\tother\tA:10 \tG:10 \t\t\n",
+			// C-G at 9 expected reads, below -min_depth although its fraction passes, and T-T of the marker with REF ".".
+			"b.verbose.csv": "chr1\t10\tmh\tC-T:40 \tC-G:9 \t\t\nchr1\t50\tdot\tA-G:40 \tT-T:20 \t\t\n",
+		}
+		verbose []string
+	)
+	for name, content := range samples {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		verbose = append(verbose, path)
+	}
+
+	novel := CollectRareAlleles(panel, verbose, 10, 0.1)
+	if len(novel) != 2 || len(novel["mh"]) != 1 || novel["dot"]["T-T"] == nil {
+		t.Fatalf("collected %v, want C-G of mh and T-T of dot", novel)
+	}
+	record := novel["mh"]["C-G"]
+	if record == nil || len(record.Samples) != 1 || record.Samples[0] != "a" || record.Depth != 13 {
+		t.Errorf("C-G collected as %+v, want 13 reads in sample a", record)
+	}
+	proposed := ProposeAlleles(novel, 1)
+	if len(proposed) != 2 || proposed[0].Allele != "T-T" || proposed[1].Allele != "C-G" {
+		t.Errorf("proposed %v, want T-T of dot and C-G of mh", proposed)
+	}
+	if proposed = ProposeAlleles(novel, 2); len(proposed) != 0 {
+		t.Errorf("proposed %v from one sample, want none with 2 samples", proposed)
+	}
+
+	// Lowering -min_depth to 9 expected reads counts sample b.
+	if record := CollectRareAlleles(panel, verbose, 9, 0.1)["mh"]["C-G"]; record == nil || len(record.Samples) != 2 {
+		t.Errorf("C-G collected as %+v at -min_depth 9, want both samples", record)
+	}
+}
+
+func TestWriteDiscoveredVCF(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		panel = filepath.Join(dir, "panel.vcf")
+		out   = filepath.Join(dir, "out.vcf")
+	)
+	err := os.WriteFile(panel, []byte("##fileformat=VCFv4.2\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"+
+		"chr1\t10\tmh\t.\t.\t.\tPASS\tOFFSET=5\n"+
+		"chr1\t50\tmh2\tC-T\tA-G\t.\tPASS\tOFFSET=5\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDiscoveredVCF(panel, out, []NovelAllele{
+		{Marker: "mh", Allele: "C-G", Samples: []string{"a", "b"}, Depth: 30},
+		{Marker: "mh2", Allele: "T-T", Samples: []string{"a"}, Depth: 12},
+	}, []string{"a", "b"})
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 6 || lines[0] != "##fileformat=VCFv4.2" || !strings.HasPrefix(lines[1], "##discover=") ||
+		!strings.HasPrefix(lines[2], "##INFO=<ID=NOVEL") || !strings.HasPrefix(lines[3], "#CHROM") {
+		t.Fatalf("header written as %q", lines)
+	}
+	if want := "chr1\t10\tmh\t.\tC-G\t.\tPASS\tOFFSET=5;NOVEL=C-G:2:30"; lines[4] != want {
+		t.Errorf("record written as %q, want %q", lines[4], want)
+	}
+	if want := "chr1\t50\tmh2\tC-T\tA-G,T-T\t.\tPASS\tOFFSET=5;NOVEL=T-T:1:12"; lines[5] != want {
+		t.Errorf("record written as %q, want %q", lines[5], want)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// writeErrorModel writes the error rates in total, by cycle and by quality to a .error.tab file.
+func writeErrorModel(model *typing.ErrorModel) {
+	handle, err := os.Create(*OUT + ".error.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Type\tKey\tBases\tMismatches\tRate\n")
+	check(err)
+	row := func(kind, key string, count [2]uint64) {
+		var rate float64
+		if count[0] > 0 {
+			rate = float64(count[1]) / float64(count[0])
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%s\t%d\t%d\t%0.6f\n", kind, key, count[0], count[1], rate))
+		check(err)
+	}
+	row("ALL", "*", [2]uint64{model.Bases, model.Mismatches})
+	for cycle, count := range model.Cycle {
+		if count[0] > 0 {
+			row("CYCLE", fmt.Sprint(cycle+1), count)
+		}
+	}
+	for quality, count := range model.Quality {
+		if count[0] > 0 {
+			row("QUALITY", fmt.Sprint(quality), count)
+		}
+	}
+	check(writer.Flush())
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// openReference opens the reference FASTA with its index, and saves the index next to the FASTA file if it was built.
+// The index is not saved in a read-only directory, and built again next time.
+func openReference(handle *os.File) *typing.FASTA {
+	reference, err := typing.NewFASTA(handle)
+	check(err)
+	if err = reference.SaveIndex(); err != nil {
+		log.Printf("FASTA index is not saved: %v", err)
+	}
+	return reference
+}
+
+// writeFlank writes n bases flanking each marker to a .flank.tab file.
+func writeFlank(markers []typing.GeneticMarker, reference *typing.FASTA, n int64) {
+	handle, err := os.Create(*OUT + ".flank.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Marker\tCHROM\tStart\tEnd\tLeft\tRight\n")
+	check(err)
+	for _, marker := range markers {
+		start, end := typing.MarkerSpan(marker)
+		left, right := reference.Flank(marker.GetCHROM(), start, end, n)
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%s\t%d\t%d\t%s\t%s\n", marker.GetID(), marker.GetCHROM(), start, end, left, right))
+		check(err)
+	}
+	check(writer.Flush())
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// writeMergeStat writes the merge rate of read pairs of each amplicon to a .merge.tab file.
+func writeMergeStat(index *typing.AmpliconIndex, stat *typing.MergeStat) {
+	handle, err := os.Create(*OUT + ".merge.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Amplicon\tCHROM\tStart\tEnd\tPairs\tMerged\tUnmerged\tMergeRate\n")
+	check(err)
+	for i, amplicon := range index.Amplicons {
+		var (
+			pairs = stat.Merged[i] + stat.Unmerged[i]
+			rate  float64
+		)
+		if pairs > 0 {
+			rate = float64(stat.Merged[i]) / float64(pairs)
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%d\t%d\t%0.4f\n", amplicon.Name, amplicon.CHROM,
+			amplicon.Start, amplicon.Start+int64(len(amplicon.Seq))-1, pairs, stat.Merged[i], stat.Unmerged[i], rate))
+		check(err)
+	}
+	_, err = writer.WriteString(fmt.Sprintf("*\t*\t0\t0\t%d\t0\t0\t0\n", stat.Unassigned))
+	check(err)
+	check(writer.Flush())
+}
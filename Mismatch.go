@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// writeMismatchFilter writes the number of reads decided by each mismatch policy of microhaplotypes to a .mismatch.tab file.
+func writeMismatchFilter(markers []typing.GeneticMarker) {
+	handle, err := os.Create(*OUT + ".mismatch.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Marker\tChecked\tRejected\tTolerated\tKnownSNP\tLowQuality\n")
+	check(err)
+	for _, marker := range markers {
+		if mh, ok := marker.(typing.MH); ok && mh.Filter != nil {
+			f := mh.Filter
+			_, err = writer.WriteString(fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%d\n",
+				mh.ID, f.Checked, f.Rejected, f.Tolerated, f.KnownSNP, f.LowQuality))
+			check(err)
+		}
+	}
+	check(writer.Flush())
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// writeMixture writes the ranked major and minor contributor genotypes of each marker to a .mixture.tab file.
+func writeMixture(markers []typing.GeneticMarker) {
+	var knownProfile map[string][2]string
+	if *known != "" {
+		handle, err := os.Open(*known)
+		check(err)
+		knownProfile, err = typing.ReadGenotypeTab(handle)
+		check(err)
+		check(handle.Close())
+	}
+
+	mx, result := typing.DeconvolveMixture(markers, knownProfile)
+
+	handle, err := os.Create(*OUT + ".mixture.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString(fmt.Sprintf("#Mx=%0.2f\n", mx))
+	check(err)
+	_, err = writer.WriteString("#Marker\tRank\tMajor1\tMajor2\tMinor1\tMinor2\tWeight\n")
+	check(err)
+	for _, m := range result {
+		for i, c := range m.Combinations {
+			if i == typing.MixtureTopN {
+				break
+			}
+			_, err = writer.WriteString(fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s\t%0.4f\n",
+				m.ID, i+1, c.Major[0], c.Major[1], c.Minor[0], c.Minor[1], c.Weight))
+			check(err)
+		}
+	}
+	check(writer.Flush())
+}
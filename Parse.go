@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// writeRejects writes the malformed records skipped with -lenient to a .rejects.tab file.
+func writeRejects(rejected []*typing.ParseError) {
+	handle, err := os.Create(*OUT + ".rejects.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#File\tLine\tError\tRecord\n")
+	check(err)
+	for _, reject := range rejected {
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%d\t%v\t%s\n", reject.File, reject.Line, reject.Err, reject.Record))
+		check(err)
+	}
+	check(writer.Flush())
+	fmt.Printf("Skipped %d malformed records, listed in %s.rejects.tab\n", len(rejected), *OUT)
+}
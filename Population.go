@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"TypingMarkers/typing"
+)
+
+// writePosterior writes the posterior genotypes of microhaplotypes with population priors to a .posterior.tab file:
+// the most likely genotype and its probability, the expected copies (dosage) of each allele, and the genotypes
+// with a probability of at least 0.001.
+func writePosterior(markers []typing.GeneticMarker) {
+	handle, err := os.Create(*OUT + ".posterior.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Marker\tPloidy\tGenotype\tPosterior\tDosage\tGenotypes\n")
+	check(err)
+	for _, marker := range markers {
+		mh, ok := marker.(typing.MH)
+		if !ok {
+			continue
+		}
+		posteriors := mh.PosteriorGenotypes()
+		if len(posteriors) == 0 {
+			continue
+		}
+		var (
+			dosage  = typing.Dosage(posteriors)
+			alleles []string
+			doses   []string
+			likely  []string
+		)
+		for allele := range dosage {
+			alleles = append(alleles, allele)
+		}
+		sort.Strings(alleles)
+		for _, allele := range alleles {
+			if dosage[allele] >= 0.005 {
+				doses = append(doses, fmt.Sprintf("%s:%0.2f", allele, dosage[allele]))
+			}
+		}
+		for _, posterior := range posteriors {
+			if posterior.Probability >= 0.001 {
+				likely = append(likely, fmt.Sprintf("%s:%0.3f", strings.Join(posterior.Genotype, "/"), posterior.Probability))
+			}
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%d\t%s\t%0.4f\t%s\t%s\n", mh.ID, mh.Ploidy(),
+			strings.Join(posteriors[0].Genotype, "/"), posteriors[0].Probability,
+			strings.Join(doses, " "), strings.Join(likely, " ")))
+		check(err)
+	}
+	check(writer.Flush())
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+
+	"TypingMarkers/typing"
+)
+
+// writePrimerStat writes the reads of each amplicon and off-target reads to a .primer.tab file.
+func writePrimerStat(set *typing.PrimerSet) {
+	handle, err := os.Create(*OUT + ".primer.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Amplicon\tCHROM\tStart\tEnd\tReads\tBothPrimers\tOnePrimer\tMaskedBases\n")
+	check(err)
+	var amplicons = append([]*typing.PrimerAmplicon{}, set.Amplicons...)
+	sort.SliceStable(amplicons, func(i, j int) bool {
+		return amplicons[i].Name < amplicons[j].Name
+	})
+	for _, a := range amplicons {
+		start, end := a.Span()
+		_, err = writer.WriteString(fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			a.Name, a.CHROM, start, end, a.Reads, a.Complete, a.Partial, a.Masked))
+		check(err)
+	}
+	_, err = writer.WriteString(fmt.Sprintf("OFF_TARGET\t*\t0\t0\t%d\t0\t0\t0\n", set.OffTarget))
+	check(err)
+	check(writer.Flush())
+}
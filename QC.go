@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bufio"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// writeQC writes one QC row per marker to a .qc.tab file.
+func writeQC(markers []typing.GeneticMarker) {
+	handle, err := os.Create(*OUT + ".qc.tab")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	_, err = writer.WriteString("#Marker\tType\tDepth\tPartialFrac\tRareDepth\tAlleleBalance\tStrandBalance\tPloidy\tGenotype\tStatus\tReason\n")
+	check(err)
+	for _, marker := range markers {
+		_, err = writer.WriteString(typing.NewQCRecord(marker).String() + "\n")
+		check(err)
+	}
+	check(writer.Flush())
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"TypingMarkers/typing"
+)
+
+// Colors of the charts in HTML report.
+const (
+	colorKnown = "#4e79a7"
+	colorRare  = "#e15759"
+	colorDepth = "#59a14f"
+)
+
+// reportMarker is a marker row of the HTML report.
+type reportMarker struct {
+	typing.QCRecord
+	Chart template.HTML
+}
+
+// reportData is rendered by reportTemplate.
+type reportData struct {
+	Version    string
+	Date       string
+	SAM, VCF   string
+	ErrorRate  float64
+	Markers    []reportMarker
+	DepthChart template.HTML
+	Status     map[string]int
+}
+
+// barChart draws a horizontal bar chart as inline SVG. Bars are drawn in given order and colors.
+func barChart(labels []string, values []float64, colors []string) template.HTML {
+	const (
+		labelWidth = 140
+		barWidth   = 300
+		rowHeight  = 18
+	)
+	var maximum float64
+	for _, v := range values {
+		maximum = math.Max(maximum, v)
+	}
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="11" font-family="monospace">`,
+		labelWidth+barWidth+60, rowHeight*len(labels)+4))
+	for i, label := range labels {
+		var width float64
+		if maximum > 0 {
+			width = values[i] / maximum * barWidth
+		}
+		y := i*rowHeight + 2
+		s.WriteString(fmt.Sprintf(`<text x="%d" y="%d" text-anchor="end">%s</text>`, labelWidth-4, y+12, template.HTMLEscapeString(label)))
+		s.WriteString(fmt.Sprintf(`<rect x="%d" y="%d" width="%0.1f" height="%d" fill="%s"/>`, labelWidth, y, width, rowHeight-4, colors[i]))
+		s.WriteString(fmt.Sprintf(`<text x="%0.1f" y="%d">%0.f</text>`, float64(labelWidth)+width+4, y+12, values[i]))
+	}
+	s.WriteString(`</svg>`)
+	return template.HTML(s.String())
+}
+
+// alleleChart draws the depth of known and rare alleles of a marker, sorted by depth.
+func alleleChart(marker typing.GeneticMarker) template.HTML {
+	type bar struct {
+		label string
+		value float64
+		color string
+	}
+	var bars []bar
+	for allele, n := range marker.AlleleDepth() {
+		if n > 0 {
+			bars = append(bars, bar{allele, n, colorKnown})
+		}
+	}
+	var rare map[string]float64
+	switch m := marker.(type) {
+	case typing.MH:
+		rare = m.RareAlleles
+	case typing.InDel:
+		rare = m.RareAlleles
+	}
+	for allele, n := range rare {
+		bars = append(bars, bar{allele, n, colorRare})
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].value == bars[j].value {
+			return bars[i].label < bars[j].label
+		}
+		return bars[i].value > bars[j].value
+	})
+	if len(bars) == 0 {
+		return ""
+	}
+	var (
+		labels []string
+		values []float64
+		colors []string
+	)
+	for _, b := range bars {
+		labels, values, colors = append(labels, b.label), append(values, b.value), append(colors, b.color)
+	}
+	return barChart(labels, values, colors)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>TypingMarkers report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; font-size: 13px; vertical-align: top; }
+th { background: #eee; }
+.PASS { background: #e6f4ea; }
+.LOW_CONF { background: #fff4ce; }
+.NO_CALL { background: #fde7e9; }
+code { font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>TypingMarkers report</h1>
+<p>Version {{.Version}}, generated {{.Date}}<br>SAM: <code>{{.SAM}}</code><br>VCF: <code>{{.VCF}}</code><br>Sequencing error rate: {{printf "%0.6f" .ErrorRate}}</p>
+<p>{{range $status, $n := .Status}}{{$status}}: {{$n}} &nbsp; {{end}}</p>
+
+<h2>Depth across markers</h2>
+{{.DepthChart}}
+
+<h2>Genotypes</h2>
+<table>
+<tr><th>Marker</th><th>Type</th><th>Ploidy</th><th>Genotype</th><th>Depth</th><th>Status</th><th>Reason</th></tr>
+{{range .Markers}}<tr class="{{.Status}}"><td><a href="#{{.Marker}}">{{.Marker}}</a></td><td>{{.Type}}</td><td>{{.Ploidy}}</td><td>{{.GenotypeString}}</td><td>{{printf "%0.f" .Depth}}</td><td>{{.Status}}</td><td>{{range .Reasons}}{{.}} {{end}}</td></tr>
+{{end}}</table>
+
+<h2>Allele depth</h2>
+<p><span style="color:` + colorKnown + `">&#9632;</span> known allele &nbsp; <span style="color:` + colorRare + `">&#9632;</span> rare allele</p>
+{{range .Markers}}<h3 id="{{.Marker}}">{{.Marker}} <small class="{{.Status}}">{{.Status}}</small></h3>
+{{if .Chart}}{{.Chart}}{{else}}<p>No read.</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// writeReport writes a self-contained HTML report with embedded SVG charts, requiring no external dependency.
+func writeReport(markers []typing.GeneticMarker, model *typing.ErrorModel) {
+	var (
+		data = reportData{
+			Version:   VERSION,
+			Date:      time.Now().Format("2006-01-02 15:04"),
+			SAM:       *SAMPath,
+			VCF:       *VCF,
+			ErrorRate: model.Rate(),
+			Status:    make(map[string]int),
+		}
+		labels []string
+		depths []float64
+		colors []string
+	)
+	for _, marker := range markers {
+		record := typing.NewQCRecord(marker)
+		data.Markers = append(data.Markers, reportMarker{QCRecord: record, Chart: alleleChart(marker)})
+		data.Status[record.Status()]++
+		labels, depths, colors = append(labels, record.Marker), append(depths, record.Depth), append(colors, colorDepth)
+	}
+	data.DepthChart = barChart(labels, depths, colors)
+
+	handle, err := os.Create(*OUT + ".report.html")
+	check(err)
+	defer func() {
+		check(handle.Close())
+	}()
+	writer := bufio.NewWriter(handle)
+	check(reportTemplate.Execute(writer, data))
+	check(writer.Flush())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"TypingMarkers/typing"
+)
+
+func TestWriteReport(t *testing.T) {
+	markers, err := typing.NewVCFFormat(strings.NewReader("chr1\t5\tsnp\tA\tG\t.\tPASS\t.\n" +
+		"chr1\t10\tmh\tC-T\tG-A\t.\tPASS\tOFFSET=5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reads of A and C-T, and of G and G-A, on both strands.
+	var sam strings.Builder
+	for i, flag := range []string{"0", "16", "0", "16", "0", "16", "0", "16", "0", "16"} {
+		name := string(rune('a' + i))
+		sam.WriteString("r" + name + "\t" + flag + "\tchr1\t1\t60\t20M\t*\t0\t0\tTTTTATTTTCTTTTTTTTTT\t*\n")
+		sam.WriteString("a" + name + "\t" + flag + "\tchr1\t1\t60\t20M\t*\t0\t0\tTTTTGTTTTGTTTTATTTTT\t*\n")
+	}
+	result, err := typing.NewTyper(markers, typing.DefaultOptions()).Type(strings.NewReader(sam.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(out string) { *OUT = out }(*OUT)
+	*OUT = filepath.Join(t.TempDir(), "sample")
+	writeReport(result.Markers, result.ErrorModel)
+	html, err := os.ReadFile(*OUT + ".report.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		`<td><a href="#snp">snp</a></td><td>SNP</td><td>2</td><td>A/G</td><td>20</td><td>PASS</td>`,
+		`<td><a href="#mh">mh</a></td><td>MH</td><td>2</td><td>C-T/G-A</td><td>20</td><td>PASS</td>`,
+		`<h3 id="mh">mh <small class="PASS">PASS</small></h3>`,
+	} {
+		if !strings.Contains(string(html), row) {
+			t.Errorf("report misses %s", row)
+		}
+	}
+}
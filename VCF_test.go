@@ -8,24 +8,9 @@ import (
 	"os/exec"
 	"strings"
 	"testing"
-)
-
-func TestAdjustPos(t *testing.T) {
-	fmt.Println(AdjustPos(80, "65M2D1I35M"))
-}
 
-func TestCountMatchSNPInMH(t *testing.T) {
-	var pair = [][2]AlleleMH{
-		{"A-T-A-G-T", "A-T-A-G-T"}, //Match == 5
-		{"A-T-T-G-T", "A-T-A-G-T"}, // Never Match == -1
-		{".-.-.-A-T", "A-T-A-G-T"}, // not overhang == -1
-		{".-.-.-G-T", "A-T-A-G-T"}, // left overhang == 2
-		{"A-T-A-.-.", "A-T-A-G-T"}, // right overhang == 3
-	}
-	for _, v := range pair {
-		fmt.Printf("%s set to %d\n", v[0], CountMatchSNPInMH(v[0], v[1]))
-	}
-}
+	"TypingMarkers/typing"
+)
 
 var SAMPLES = [][2]string{
 	{"1005", "1005"},
@@ -274,11 +259,11 @@ func TestRunCommand(t *testing.T) {
 }
 
 type PanelMH struct {
-	panel map[string][2]AlleleMH
+	panel map[string][2]typing.AlleleMH
 }
 
 func (p PanelMH) String() string {
-	var sortedAllele []AlleleMH
+	var sortedAllele []typing.AlleleMH
 	for _, m := range MARKERS {
 		sortedAllele = append(sortedAllele, p.panel[m][0], p.panel[m][1])
 	}
@@ -286,14 +271,14 @@ func (p PanelMH) String() string {
 }
 
 func Read(file *os.File) PanelMH {
-	var panel = make(map[string][2]AlleleMH, len(MARKERS))
+	var panel = make(map[string][2]typing.AlleleMH, len(MARKERS))
 	reader := bufio.NewScanner(file)
 	for reader.Scan() {
 		if reader.Text()[0] == '#' {
 			continue
 		}
 		if sub := strings.Split(reader.Text(), "\t"); len(sub) == 3 {
-			panel[sub[0]] = [2]AlleleMH{sub[1], sub[2]}
+			panel[sub[0]] = [2]typing.AlleleMH{sub[1], sub[2]}
 		}
 	}
 	return PanelMH{panel: panel}
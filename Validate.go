@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"TypingMarkers/typing"
+)
+
+// validate is the sub-command checking the consistency of a panel VCF.
+func validate(args []string) {
+	var (
+		flags     = flag.NewFlagSet("validate", flag.ExitOnError)
+		panelPath = flags.String("VCF", "", "specify panel VCF path")
+		fastaPath = flags.String("FASTA", "", "specify reference FASTA path to check REF alleles (optional)")
+	)
+	check(flags.Parse(args))
+	if *panelPath == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var reference *typing.FASTA
+	if *fastaPath != "" {
+		handle, err := os.Open(*fastaPath)
+		check(err)
+		defer handle.Close()
+		reference = openReference(handle)
+	}
+
+	handle, err := os.Open(*panelPath)
+	check(err)
+	issues, err := typing.ValidatePanel(handle, reference)
+	check(err)
+	check(handle.Close())
+
+	for _, issue := range issues {
+		fmt.Printf("%s:%s\n", *panelPath, issue)
+	}
+	if len(issues) > 0 {
+		fmt.Printf("%d issues were found in %s.\n", len(issues), *panelPath)
+		os.Exit(1)
+	}
+	fmt.Printf("%s is valid.\n", *panelPath)
+}
@@ -7,16 +7,53 @@ import (
 	"log"
 	"os"
 	"time"
+
+	"TypingMarkers/typing"
 )
 
+// defaults are the default options of typing.
+var defaults = typing.DefaultOptions()
+
 var (
 	OUT     = flag.String("OUT", "result", "specify the prefix of all output files")
 	SAMPath = flag.String("SAM", "", "specify SAM path")
-	VCF     = flag.String("VCF", "", "specify SNP path")
-	perc    = flag.Bool("p", false, "print percentage in verbose omitting % symbol")
-	minPerc = flag.Float64("min_perc", 0, "specify minimum percentage reported alleles in verbose, range 0 to 100")
-	minFreq = flag.Float64("min_freq", 0.03, "specify minimum frequency of each "+
+
+	FASTQPath          = flag.String("FASTQ", "", "specify FASTQ path (plain or gzipped) of amplicon reads, instead of -SAM")
+	FASTQ2Path         = flag.String("FASTQ2", "", "specify FASTQ path of read 2, merged with -FASTQ by overlap before typing (optional)")
+	minOverlap         = flag.Int("min_overlap", 10, "specify minimum overlap (bp) of read pairs to be merged for -FASTQ2")
+	maxOverlapMismatch = flag.Float64("max_overlap_mismatch", 0.1, "specify maximum mismatch rate in the overlap of read pairs to be merged for -FASTQ2")
+	ampliconPath       = flag.String("AMPLICON", "", "specify FASTA of amplicon reference sequences for -FASTQ")
+	primerPath         = flag.String("PRIMER", "", "specify BED of amplicon primers, bases under primers are masked before typing (optional)")
+	primerTolerance    = flag.Int64("primer_tolerance", defaults.PrimerTolerance, "specify maximum distance (bp) between read end and primer end for -PRIMER")
+	dropOffTarget      = flag.Bool("drop_offtarget", defaults.DropOffTarget, "drop reads matching no primer of -PRIMER")
+	ampliconFlank      = flag.Int64("amplicon_flank", 100, "specify flanking length of amplicons built from -FASTA for -FASTQ without -AMPLICON")
+	VCF                = flag.String("VCF", "", "specify SNP path")
+	perc               = flag.Bool("p", defaults.Percent, "print percentage in verbose omitting % symbol")
+	minPerc            = flag.Float64("min_perc", defaults.MinPercent, "specify minimum percentage reported alleles in verbose, range 0 to 100")
+	minFreq            = flag.Float64("min_freq", defaults.MinFreq, "specify minimum frequency of each "+
 		"allele, ranging from 0 for high depth to 1 for low depth")
+	mixture   = flag.Bool("mixture", false, "deconvolve a two-person mixture into major and minor contributors")
+	known     = flag.String("known", "", "specify genotype file (.tab) of a known contributor in the mixture")
+	FASTAPath = flag.String("FASTA", "", "specify reference FASTA path, indexed by .fai (optional)")
+	flank     = flag.Int64("flank", 0, "specify length of flanking sequence reported for each marker, requiring -FASTA")
+
+	maxMismatch    = flag.Int("max_mismatch", defaults.MaxMismatch, "specify maximum number of non-marker mismatches in a microhaplotype read")
+	minBaseQual    = flag.Int("min_base_qual", defaults.MinBaseQual, "specify minimum base quality of a non-marker mismatch to be counted")
+	realign        = flag.Bool("realign", defaults.Realign, "type microhaplotypes by aligning reads to the sequence of each known allele (pair-HMM), requiring -FASTA")
+	longReads      = flag.Bool("long_reads", defaults.LongReads, "type long noisy reads (e.g. nanopore): SNPs are read through indels of the alignment and calls use a noisier error model")
+	indelWindow    = flag.Int64("indel_window", defaults.IndelWindow, "specify distance (bp) to an indel of a long read within which SNPs are not typed, for -long_reads")
+	knownSNPsPath  = flag.String("known_snps", "", "specify VCF of known population SNPs masked inside microhaplotypes (optional)")
+	strandBiasP    = flag.Float64("strand_bias", defaults.StrandBiasP, "specify p-value of Fisher strand bias test below which an allele is excluded from genotype, 0 to disable")
+	minDepth       = flag.Float64("min_depth", defaults.MinDepth, "specify minimum depth of a marker below which the call is flagged LOW_DEPTH in QC")
+	report         = flag.Bool("html", false, "write a self-contained HTML report with charts of allele depth and QC")
+	minBalance     = flag.Float64("min_balance", defaults.MinBalance, "specify minimum ratio of minor to major allele depth of a heterozygote in QC")
+	errorCall      = flag.Bool("error_call", defaults.ErrorCall, "exclude alleles explained by the sequencing error rate estimated from the sample")
+	stutterRatio   = flag.Float64("stutter", defaults.Stutter, "specify maximum ratio of an STR stutter product (n-1/n+1) to its parent allele, filtered from the genotype")
+	ploidy         = flag.Int("ploidy", defaults.Ploidy, "specify number of copies of autosomal markers without PLOIDY in the panel, e.g. 4 for tetraploid species")
+	sex            = flag.String("sex", defaults.Sex, "specify sex of the sample (male or female): X-linked markers are haploid in males, Y-linked markers absent in females")
+	lenient        = flag.Bool("lenient", defaults.Lenient, "skip malformed SAM and VCF records instead of stopping, listed in a .rejects.tab file")
+	populationPath = flag.String("population", "", "specify genotypes of a reference population (e.g. data/genotype-data.tab) as priors of posterior genotype probabilities of microhaplotypes")
+	MHGroupPath    = flag.String("MH_GROUP", "", "specify BED (CHROM START END MH_ID) or TSV (SNP_ID MH_ID) grouping SNP records of -VCF into microhaplotypes")
 )
 
 const (
@@ -25,15 +62,29 @@ const (
 )
 
 func main() {
+	// Sub-commands have their own flags, the default command types a SAM file.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "discover":
+			discover(os.Args[2:])
+			return
+		case "design":
+			design(os.Args[2:])
+			return
+		case "validate":
+			validate(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
-	if *VCF == "" || *SAMPath == "" {
+	if *VCF == "" || (*SAMPath == "" && *FASTQPath == "") {
 		flag.Usage()
 		fmt.Println(VERSION, UpdateDate)
 		os.Exit(1)
 	}
 	//filterSAM()
 	statAlleles()
-	//plotStat()
 
 	pointInTime := time.Now()
 	fmt.Printf("Congratulations, the program has finished successfully! (now %d:%d)\n", pointInTime.Hour(), pointInTime.Minute())
@@ -52,6 +103,30 @@ func main() {
 //	}
 //}
 
+// typingOptions collects the options of typing given by flags.
+func typingOptions() typing.Options {
+	var options = typing.DefaultOptions()
+	options.MinFreq = *minFreq
+	options.StrandBiasP = *strandBiasP
+	options.ErrorCall = *errorCall
+	options.Stutter = *stutterRatio
+	options.Ploidy = *ploidy
+	options.Sex = *sex
+	options.MaxMismatch = *maxMismatch
+	options.MinBaseQual = *minBaseQual
+	options.Realign = *realign
+	options.LongReads = *longReads
+	options.IndelWindow = *indelWindow
+	options.PrimerTolerance = *primerTolerance
+	options.DropOffTarget = *dropOffTarget
+	options.Lenient = *lenient
+	options.MinDepth = *minDepth
+	options.MinBalance = *minBalance
+	options.Percent = *perc
+	options.MinPercent = *minPerc
+	return options
+}
+
 func statAlleles() {
 	outHandle, err := os.Create(*OUT + ".tab")
 	outVerboseHandle, err := os.Create(*OUT + ".verbose.csv")
@@ -75,44 +150,116 @@ func statAlleles() {
 	defer handleVCF.Close()
 	check(err)
 
+	var (
+		markers  []typing.GeneticMarker
+		rejected []*typing.ParseError
+		options  = typingOptions()
+	)
+	if *lenient {
+		markers, rejected, err = typing.NewVCFFormatLenient(handleVCF)
+	} else {
+		markers, err = typing.NewVCFFormat(handleVCF)
+	}
+	check(err)
+
+	if *MHGroupPath != "" {
+		handleGroup, err := os.Open(*MHGroupPath)
+		check(err)
+		groups, err := typing.NewMHGroups(handleGroup)
+		check(err)
+		var warnings []error
+		markers, warnings, err = typing.GroupSNPs(markers, groups)
+		check(err)
+		for _, warning := range warnings {
+			log.Println(warning)
+		}
+		check(handleGroup.Close())
+	}
+
+	if *populationPath != "" {
+		handlePopulation, err := os.Open(*populationPath)
+		check(err)
+		population, err := typing.ReadPopulation(handlePopulation)
+		check(err)
+		typing.FillPopulation(markers, population)
+		check(handlePopulation.Close())
+	}
+
+	if *knownSNPsPath != "" {
+		handleKnown, err := os.Open(*knownSNPsPath)
+		check(err)
+		options.KnownSNPs, err = typing.NewKnownSNPs(handleKnown)
+		check(err)
+		check(handleKnown.Close())
+	}
+
+	if *FASTAPath != "" {
+		handleFASTA, err := os.Open(*FASTAPath)
+		check(err)
+		defer handleFASTA.Close()
+		options.Reference = openReference(handleFASTA)
+		for _, warning := range typing.FillReference(markers, options.Reference) {
+			log.Println(warning)
+		}
+	}
+
+	if *FASTQPath != "" {
+		*SAMPath = *OUT + ".amplicon.sam"
+		typeFASTQ(markers, options.Reference)
+	}
+
 	handleSAM, err := os.Open(*SAMPath)
 	defer handleSAM.Close()
 	check(err)
 
-	markers := NewVCFFormat(handleVCF)
+	if *primerPath != "" {
+		handlePrimer, err := os.Open(*primerPath)
+		check(err)
+		options.Primers, err = typing.NewPrimerSet(handlePrimer)
+		check(err)
+		check(handlePrimer.Close())
+	}
+
+	// Type all markers first, so that the outputs depending on the whole panel (e.g. mixture) can be produced.
+	result, err := typing.NewTyper(markers, options).Type(handleSAM)
+	check(err)
+	markers = result.Markers
+	if *lenient {
+		writeRejects(append(rejected, result.Rejected...))
+	}
+	if result.Primers != nil {
+		writePrimerStat(result.Primers)
+	}
+	model := result.ErrorModel
+	writeErrorModel(model)
+	fmt.Printf("Sequencing error rate: %0.6f (%d mismatches in %d invariant bases)\n", model.Rate(), model.Mismatches, model.Bases)
 
 	for _, marker := range markers {
-
-		switch m := marker.(type) {
-		case SNP:
-			ExtractSNP(handleSAM, &m)
-			_, err = writer.WriteString(m.String() + "\n")
-			check(err)
-			_, err = writerVerbose.WriteString(m.VerboseString() + "\n")
-			check(err)
-		case MH:
-			ExtractMHAlleles(handleSAM, &m)
-			_, err = writer.WriteString(m.String() + "\n")
-			check(err)
-			_, err = writerVerbose.WriteString(m.VerboseString() + "\n")
-			check(err)
-		}
+		_, err = writer.WriteString(marker.String() + "\n")
+		check(err)
+		_, err = writerVerbose.WriteString(marker.VerboseString() + "\n")
+		check(err)
 	}
 	err = writer.Flush()
 	check(err)
 	err = writerVerbose.Flush()
 	check(err)
-}
 
-//func plotStat() {
-//	cmd := exec.Command("Rscript",
-//		"plotStat.R",
-//		*OUT+".csv")
-//	_, err := cmd.Output()
-//	if err != nil {
-//		log.Panic(err)
-//	}
-//}
+	if *mixture {
+		writeMixture(markers)
+	}
+	writeMismatchFilter(markers)
+	writeQC(markers)
+	if *populationPath != "" {
+		writePosterior(markers)
+	}
+	if *report {
+		writeReport(markers, model)
+	}
+	if options.Reference != nil && *flank > 0 {
+		writeFlank(markers, options.Reference, *flank)
+	}
+}
 
 // check function acts as a utility tool for entire error check.
 func check(err error) {
@@ -0,0 +1,443 @@
+package typing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// kmerSize is the length of k-mers indexing amplicon sequences.
+	kmerSize = 15
+	// minKmerVotes is the minimum number of k-mers shared by a read and its amplicon.
+	minKmerVotes = 2
+	// alignBand is the half width of the diagonal band in which a read is aligned.
+	alignBand = 16
+
+	// Scores of banded alignment with affine gap penalty.
+	scoreMatch    = 2
+	scoreMismatch = -4
+	scoreGapOpen  = 8
+	scoreGapExt   = 2
+)
+
+// Amplicon is a targeted sequence whose first base is at Start (1-based) of CHROM.
+// Amplicons given as FASTA are their own coordinates, i.e. CHROM is the name and Start is 1.
+type Amplicon struct {
+	Name  string
+	CHROM string
+	Start int64
+	Seq   string
+}
+
+// NewAmplicons imports amplicon reference sequences from a FASTA file.
+func NewAmplicons(file *os.File) (amplicons []Amplicon, err error) {
+	fasta, err := NewFASTA(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range fasta.Names {
+		amplicons = append(amplicons, Amplicon{Name: name, CHROM: name, Start: 1, Seq: fasta.Fetch(name, 1, fasta.Index[name].Length)})
+	}
+	return amplicons, fasta.Err()
+}
+
+// MarkerSpan returns the first and last reference positions of a marker.
+func MarkerSpan(marker GeneticMarker) (start, end int64) {
+	start, end = marker.GetPOS(), marker.GetPOS()
+	switch m := marker.(type) {
+	case SNP:
+		end = m.POS + int64(len(m.REF)) - 1
+	case MH:
+		end = m.POS + int64(m.OffSet[len(m.OffSet)-1])
+	case InDel:
+		end = m.End()
+	case STR:
+		end = m.End
+	}
+	return
+}
+
+// BuildAmplicons extracts amplicons from the reference, spanning each marker with flank bases on both sides.
+// Overlapping amplicons are merged.
+func BuildAmplicons(markers []GeneticMarker, reference *FASTA, flank int64) (amplicons []Amplicon) {
+	type region struct {
+		chrom      string
+		start, end int64
+	}
+	var regions []region
+	for _, marker := range markers {
+		start, end := MarkerSpan(marker)
+		regions = append(regions, region{marker.GetCHROM(), max(1, start-flank), end + flank})
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].chrom == regions[j].chrom {
+			return regions[i].start < regions[j].start
+		}
+		return regions[i].chrom < regions[j].chrom
+	})
+	var merged []region
+	for _, r := range regions {
+		if n := len(merged); n > 0 && merged[n-1].chrom == r.chrom && r.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	for _, r := range merged {
+		seq := reference.Fetch(r.chrom, r.start, r.end)
+		if seq == "" {
+			continue
+		}
+		amplicons = append(amplicons, Amplicon{Name: fmt.Sprintf("%s:%d-%d", r.chrom, r.start, r.end), CHROM: r.chrom, Start: r.start, Seq: seq})
+	}
+	return
+}
+
+// kmerHit is an occurrence of a k-mer in an amplicon.
+type kmerHit struct {
+	amplicon int
+	offset   int
+}
+
+// AmpliconIndex assigns reads to amplicons by shared k-mers.
+type AmpliconIndex struct {
+	Amplicons []Amplicon
+	kmers     map[string][]kmerHit
+
+	// Assigned counts reads or fragments assigned to each amplicon, and Unassigned the others.
+	Assigned   []uint64
+	Unassigned uint64
+}
+
+// NewAmpliconIndex indexes k-mers of all amplicons.
+func NewAmpliconIndex(amplicons []Amplicon) *AmpliconIndex {
+	var index = &AmpliconIndex{Amplicons: amplicons, kmers: make(map[string][]kmerHit), Assigned: make([]uint64, len(amplicons))}
+	for i, amplicon := range amplicons {
+		for j := 0; j+kmerSize <= len(amplicon.Seq); j++ {
+			kmer := amplicon.Seq[j : j+kmerSize]
+			index.kmers[kmer] = append(index.kmers[kmer], kmerHit{amplicon: i, offset: j})
+		}
+	}
+	return index
+}
+
+// ampliconVote is the k-mer evidence of a read on an amplicon and strand.
+type ampliconVote struct {
+	amplicon  int
+	reverse   bool
+	votes     int
+	diagonals map[int]int
+}
+
+// assign returns the amplicon sharing most k-mers with the read or its reverse complement,
+// the most voted diagonal (amplicon offset of the first read base), and votes of the runner-up.
+func (index *AmpliconIndex) assign(seq string) (best ampliconVote, diagonal, second int, ok bool) {
+	var votes = make(map[[2]int]*ampliconVote)
+	for strand, s := range []string{seq, reverseComplement(seq)} {
+		for p := 0; p+kmerSize <= len(s); p++ {
+			for _, hit := range index.kmers[s[p:p+kmerSize]] {
+				key := [2]int{hit.amplicon, strand}
+				v, exist := votes[key]
+				if !exist {
+					v = &ampliconVote{amplicon: hit.amplicon, reverse: strand == 1, diagonals: make(map[int]int)}
+					votes[key] = v
+				}
+				v.votes++
+				v.diagonals[hit.offset-p]++
+			}
+		}
+	}
+	for _, v := range votes {
+		if v.votes > best.votes || (v.votes == best.votes && v.amplicon < best.amplicon) {
+			second = max(second, best.votes)
+			best = *v
+		} else {
+			second = max(second, v.votes)
+		}
+	}
+	if best.votes < minKmerVotes {
+		return best, 0, second, false
+	}
+	var top int
+	for d, n := range best.diagonals {
+		if n > top || (n == top && d < diagonal) {
+			diagonal, top = d, n
+		}
+	}
+	return best, diagonal, second, true
+}
+
+// Alignment is a read aligned to a reference window.
+type Alignment struct {
+	Pos   int // 0-based offset in the reference of the first aligned base.
+	Cigar string
+	MD    string
+	NM    int
+	Score int
+}
+
+// bandedAlign aligns the whole read to ref, allowing free reference ends, within alignBand of the diagonal.
+func bandedAlign(read, ref string, diagonal int) (alignment Alignment, ok bool) {
+	var (
+		lo     = max(0, diagonal-alignBand)
+		hi     = min(len(ref), diagonal+len(read)+alignBand)
+		window string
+		n      = len(read)
+		center = diagonal - lo
+		inf    = math.MinInt32 / 2
+	)
+	if lo >= hi {
+		return
+	}
+	window = ref[lo:hi]
+	m := len(window)
+
+	// H, E (deletion) and F (insertion) of Gotoh algorithm, with trace of the source matrix of each cell. Only the
+	// 2*alignBand+1 cells of each row within the band are stored.
+	var (
+		band  = 2*alignBand + 1
+		first = center - alignBand // j-i of the first cell of each row in the band.
+		// cell returns the index of row i and column j in the band matrices, or -1 outside the band.
+		cell = func(i, j int) int {
+			if k := j - i - first; j >= 0 && j <= m && k >= 0 && k < band {
+				return i*band + k
+			}
+			return -1
+		}
+		newMatrix = func(v int) []int {
+			matrix := make([]int, (n+1)*band)
+			for i := range matrix {
+				matrix[i] = v
+			}
+			return matrix
+		}
+		H, E, F                = newMatrix(inf), newMatrix(inf), newMatrix(inf)
+		traceH, traceE, traceF = newMatrix(0), newMatrix(0), newMatrix(0)
+		// score returns a cell of H, E or F, or inf outside the band.
+		score = func(matrix []int, i, j int) int {
+			if c := cell(i, j); c >= 0 {
+				return matrix[c]
+			}
+			return inf
+		}
+		substitution = func(a, b byte) int {
+			switch {
+			case a == 'N' || b == 'N':
+				return 0
+			case a == b:
+				return scoreMatch
+			default:
+				return scoreMismatch
+			}
+		}
+	)
+	for j := 0; j <= m; j++ {
+		if c := cell(0, j); c >= 0 {
+			H[c] = 0
+		}
+	}
+	for i := 1; i <= n; i++ {
+		for j := max(0, i+first); j <= min(m, i+first+band-1); j++ {
+			var (
+				c    = cell(i, j)
+				last = score(H, i-1, j)
+			)
+			if i == 1 {
+				last = 0 // the read may start anywhere in the reference, also right of the band.
+			}
+			// Insertion consumes the read.
+			if open, extend := last-scoreGapOpen, score(F, i-1, j)-scoreGapExt; open >= extend {
+				F[c], traceF[c] = open, 0
+			} else {
+				F[c], traceF[c] = extend, 2
+			}
+			H[c], traceH[c] = F[c], 2
+			if j == 0 {
+				continue
+			}
+			// Deletion consumes the reference.
+			if open, extend := score(H, i, j-1)-scoreGapOpen, score(E, i, j-1)-scoreGapExt; open >= extend {
+				E[c], traceE[c] = open, 0
+			} else {
+				E[c], traceE[c] = extend, 1
+			}
+			if E[c] > H[c] {
+				H[c], traceH[c] = E[c], 1
+			}
+			if h := score(H, i-1, j-1); h > inf && h+substitution(read[i-1], window[j-1]) >= H[c] {
+				H[c], traceH[c] = h+substitution(read[i-1], window[j-1]), 0
+			}
+		}
+	}
+
+	var end = -1
+	alignment.Score = inf
+	for j := 0; j <= m; j++ {
+		if h := score(H, n, j); h > alignment.Score {
+			alignment.Score, end = h, j
+		}
+	}
+	if end < 0 || alignment.Score <= inf/2 {
+		return
+	}
+
+	// Trace back from the best cell of the last row.
+	var (
+		ops          []byte
+		i, j, matrix = n, end, 0
+	)
+	for i > 0 {
+		switch matrix {
+		case 0:
+			switch traceH[cell(i, j)] {
+			case 0:
+				ops = append(ops, 'M')
+				i, j = i-1, j-1
+			case 1:
+				matrix = 1
+			case 2:
+				matrix = 2
+			}
+		case 1:
+			ops = append(ops, 'D')
+			if traceE[cell(i, j)] == 0 {
+				matrix = 0
+			}
+			j--
+		case 2:
+			ops = append(ops, 'I')
+			if traceF[cell(i, j)] == 0 {
+				matrix = 0
+			}
+			i--
+		}
+	}
+	alignment.Pos = lo + j
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+
+	// Build CIGAR and MD tag.
+	var (
+		cigar, md  strings.Builder
+		matchRun   int
+		query, pos = 0, j
+	)
+	for k := 0; k < len(ops); {
+		l := k
+		for l < len(ops) && ops[l] == ops[k] {
+			l++
+		}
+		cigar.WriteString(strconv.Itoa(l-k) + string(ops[k]))
+		switch ops[k] {
+		case 'M':
+			for x := k; x < l; x++ {
+				if read[query] != window[pos] && read[query] != 'N' {
+					md.WriteString(strconv.Itoa(matchRun) + string(window[pos]))
+					matchRun = 0
+					alignment.NM++
+				} else {
+					matchRun++
+				}
+				query, pos = query+1, pos+1
+			}
+		case 'D':
+			md.WriteString(strconv.Itoa(matchRun) + "^" + window[pos:pos+l-k])
+			matchRun = 0
+			alignment.NM += l - k
+			pos += l - k
+		case 'I':
+			alignment.NM += l - k
+			query += l - k
+		}
+		k = l
+	}
+	md.WriteString(strconv.Itoa(matchRun))
+	alignment.Cigar, alignment.MD = cigar.String(), md.String()
+
+	// Reads aligned with less than half of the perfect score are considered unaligned.
+	return alignment, alignment.Score >= n*scoreMatch/2
+}
+
+// Align assigns a read to its amplicon and aligns it, returning a SAM record in CHROM coordinates.
+func (index *AmpliconIndex) Align(read FASTQRecord) (*SAM, bool) {
+	vote, diagonal, second, ok := index.assign(read.Seq)
+	if !ok {
+		index.Unassigned++
+		return nil, false
+	}
+	var (
+		amplicon = index.Amplicons[vote.amplicon]
+		seq      = read.Seq
+		qual     = read.Qual
+		flag     uint64
+	)
+	if vote.reverse {
+		seq, qual, flag = reverseComplement(seq), reverseString(qual), 0x10
+	}
+	alignment, ok := bandedAlign(seq, amplicon.Seq, diagonal)
+	if !ok {
+		index.Unassigned++
+		return nil, false
+	}
+	index.Assigned[vote.amplicon]++
+
+	var mapQ uint64 = 60
+	if second > 0 {
+		mapQ = uint64(min(60, 10*(vote.votes-second)/max(1, second)))
+	}
+	if qual == "" || qual == "*" {
+		qual = "*"
+	}
+	return &SAM{
+		seqID: read.Name, flag: flag, chr: amplicon.CHROM, pos: amplicon.Start + int64(alignment.Pos),
+		mapQ: mapQ, cigar: alignment.Cigar, refNext: "*", seq: seq, qual: qual,
+		AuxiliaryTag: map[string]string{
+			"NM": strconv.Itoa(alignment.NM),
+			"MD": alignment.MD,
+			"AS": strconv.Itoa(alignment.Score),
+			"YA": amplicon.Name,
+		},
+	}, true
+}
+
+// AlignFASTQ assigns reads of a FASTQ file to amplicons and writes the alignments as SAM records, which are then
+// typed in the same way as an external alignment. Given the FASTQ of read 2, read pairs are merged before
+// alignment, and the merge counts are returned.
+func AlignFASTQ(index *AmpliconIndex, fastq, fastq2 io.Reader, w io.Writer, merge MergeOptions) (stat *MergeStat, err error) {
+	writer := bufio.NewWriter(w)
+	if fastq2 != nil {
+		if stat, err = alignPairs(index, fastq, fastq2, writer, merge); err != nil {
+			return nil, err
+		}
+		return stat, writer.Flush()
+	}
+	reader, err := NewFASTQReader(fastq)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		read, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sam, ok := index.Align(read); ok {
+			if _, err = writer.WriteString(sam.String() + "\n"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err = reader.Close(); err != nil {
+		return nil, err
+	}
+	return nil, writer.Flush()
+}
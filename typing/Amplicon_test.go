@@ -0,0 +1,22 @@
+package typing
+
+import "testing"
+
+func TestBandedAlign(t *testing.T) {
+	const ref = "GGGGGACGTTGCAAGCTTACGGATCCATGCAAGTCCCCC"
+	var cases = []struct {
+		read, cigar, md string
+		pos             int
+	}{
+		{"ACGTTGCAAGCTTACGGATCC", "21M", "21", 5},
+		{"ACGTTGCAAGATTACGGATCC", "21M", "10C10", 5},
+		{"ACGTTGCAAGCTTGGATCCATG", "13M2D9M", "13^AC9", 5},
+		{"ACGTTGCAAGCTTAAAACGGATCCATG", "13M3I11M", "24", 5},
+	}
+	for _, c := range cases {
+		alignment, ok := bandedAlign(c.read, ref, 5)
+		if !ok || alignment.Cigar != c.cigar || alignment.MD != c.md || alignment.Pos != c.pos {
+			t.Errorf("%s set to %v, want %s %s at %d", c.read, alignment, c.cigar, c.md, c.pos)
+		}
+	}
+}
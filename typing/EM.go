@@ -0,0 +1,184 @@
+package typing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// emIterations is the maximum number of EM iterations.
+	emIterations = 1000
+	// emTolerance stops EM when no allele frequency changes more than it.
+	emTolerance = 1e-8
+)
+
+// compatible reports whether a possibly partial allele agrees with a full allele at every observed SNP.
+func compatible(partial, full AlleleMH) bool {
+	if len(partial) != len(full) {
+		return false
+	}
+	for i := 0; i < len(partial); i += 2 {
+		if partial[i] != '.' && partial[i] != full[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// emClass is the reads of an observed allele, and the candidate full alleles compatible with it.
+type emClass struct {
+	allele     AlleleMH
+	reads      [2]float64
+	candidates []int
+	// likelihood of the reads given each candidate, or nil for compatible alleles, all equally likely.
+	likelihood []float64
+}
+
+// weight returns the probability of the k-th candidate of the class under the allele frequencies, unnormalized.
+func (class emClass) weight(freq []float64, k int) float64 {
+	if class.likelihood == nil {
+		return freq[class.candidates[k]]
+	}
+	return freq[class.candidates[k]] * class.likelihood[k]
+}
+
+// EstimateAlleles estimates the frequency of full alleles by expectation-maximization from fragments counted by
+// observed allele. Each partial fragment is assigned to the compatible full alleles in proportion to their current
+// frequency, until frequencies converge. It returns the expected reads of each allele on each strand, and the
+// standard deviation of expected reads due to the ambiguous assignment of partial fragments.
+// Fragments compatible with no full allele, or observing no SNP, are ignored.
+func EstimateAlleles(fragments map[AlleleMH][2]float64, alleles []AlleleMH) (strand [][2]float64, sd []float64) {
+	var (
+		classes []emClass
+		total   float64
+	)
+	for allele, reads := range fragments {
+		if strings.Trim(allele, ".-") == "" {
+			continue
+		}
+		class := emClass{allele: allele, reads: reads}
+		for i, full := range alleles {
+			if compatible(allele, full) {
+				class.candidates = append(class.candidates, i)
+			}
+		}
+		if len(class.candidates) > 0 {
+			classes = append(classes, class)
+			total += reads[Forward] + reads[Reverse]
+		}
+	}
+	// Iterate in a fixed order, so that estimates are reproducible to the last digit.
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].allele < classes[j].allele
+	})
+	return estimate(classes, len(alleles), total)
+}
+
+// estimate runs the EM of EstimateAlleles on classes of reads, with total reads, for n full alleles.
+func estimate(classes []emClass, n int, total float64) (strand [][2]float64, sd []float64) {
+	var freq = make([]float64, n)
+	strand, sd = make([][2]float64, n), make([]float64, n)
+	if total == 0 {
+		return
+	}
+	for i := range freq {
+		freq[i] = 1 / float64(n)
+	}
+	var expected = make([]float64, n)
+	for iteration := 0; iteration < emIterations; iteration++ {
+		// E-step: expected reads of each allele under current frequencies.
+		for i := range expected {
+			expected[i] = 0
+		}
+		for _, class := range classes {
+			var sum float64
+			for k := range class.candidates {
+				sum += class.weight(freq, k)
+			}
+			if sum == 0 {
+				continue
+			}
+			for k, i := range class.candidates {
+				expected[i] += (class.reads[Forward] + class.reads[Reverse]) * class.weight(freq, k) / sum
+			}
+		}
+		// M-step: frequencies maximizing the likelihood of expected reads.
+		var change float64
+		for i := range freq {
+			f := expected[i] / total
+			change = math.Max(change, math.Abs(f-freq[i]))
+			freq[i] = f
+		}
+		if change < emTolerance {
+			break
+		}
+	}
+
+	var variance = make([]float64, n)
+	for _, class := range classes {
+		var sum float64
+		for k := range class.candidates {
+			sum += class.weight(freq, k)
+		}
+		if sum == 0 {
+			continue
+		}
+		for k, i := range class.candidates {
+			p := class.weight(freq, k) / sum
+			strand[i][Forward] += class.reads[Forward] * p
+			strand[i][Reverse] += class.reads[Reverse] * p
+			variance[i] += (class.reads[Forward] + class.reads[Reverse]) * p * (1 - p)
+		}
+	}
+	for i := range sd {
+		sd[i] = math.Sqrt(variance[i])
+	}
+	return
+}
+
+// ResolveAlleles sets the depth of known and rare alleles to their expected reads estimated by EstimateAlleles.
+// Candidate full alleles are the known alleles and the full alleles observed in reads.
+func (mh *MH) ResolveAlleles() {
+	var alleles []AlleleMH
+	for allele := range mh.Alleles {
+		alleles = append(alleles, allele)
+	}
+	for allele := range mh.Fragments {
+		if _, ok := mh.Alleles[allele]; !ok && !strings.Contains(allele, ".") {
+			alleles = append(alleles, allele)
+		}
+	}
+	sort.Strings(alleles)
+
+	strand, sd := EstimateAlleles(mh.Fragments, alleles)
+	mh.Strand, mh.AlleleSD = make(map[AlleleMH][2]float64), make(map[AlleleMH]float64)
+	for i, allele := range alleles {
+		depth := strand[i][Forward] + strand[i][Reverse]
+		if _, ok := mh.Alleles[allele]; ok {
+			mh.Alleles[allele] = depth
+		} else {
+			mh.RareAlleles[allele] = depth
+		}
+		if depth > 0 {
+			mh.Strand[allele], mh.AlleleSD[allele] = strand[i], sd[i]
+		}
+	}
+}
+
+// sdToString prints the standard deviation of expected depth of each allele, e.g. "A-T:1.25 A-C:1.25 ".
+func sdToString(sd map[AlleleMH]float64) string {
+	var (
+		s       strings.Builder
+		alleles []AlleleMH
+	)
+	for allele := range sd {
+		alleles = append(alleles, allele)
+	}
+	sort.Strings(alleles)
+	for _, allele := range alleles {
+		s.WriteString(fmt.Sprintf("%s:%0.2f ", allele, sd[allele]))
+	}
+	return s.String()
+}
@@ -0,0 +1,30 @@
+package typing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateAlleles(t *testing.T) {
+	var (
+		alleles   = []AlleleMH{"A-C-G", "A-T-G", "C-T-G"}
+		fragments = map[AlleleMH][2]float64{
+			"A-C-G": {30, 0},
+			"A-T-G": {0, 10},
+			"A-.-.": {20, 0}, // shared by A-C-G and A-T-G at 3:1.
+			".-.-G": {0, 0},
+			"G-T-G": {5, 5}, // compatible with no allele.
+		}
+		want = []float64{45, 15, 0}
+	)
+	strand, sd := EstimateAlleles(fragments, alleles)
+	for i, allele := range alleles {
+		if depth := strand[i][Forward] + strand[i][Reverse]; math.Abs(depth-want[i]) > 1e-4 {
+			t.Errorf("%s depth %f, want %f", allele, depth, want[i])
+		}
+	}
+	// 20 partial reads assigned with p = 0.75 and 0.25.
+	if math.Abs(sd[0]-math.Sqrt(20*0.75*0.25)) > 1e-4 || sd[2] != 0 {
+		t.Errorf("sd %v", sd)
+	}
+}
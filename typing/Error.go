@@ -0,0 +1,182 @@
+package typing
+
+import (
+	"math"
+	"sort"
+)
+
+// errorCallP is the p-value of the binomial test below which an allele is deeper than sequencing errors explain.
+const errorCallP = 0.001
+
+// longReadErrorRate is the minimum error rate of long reads in genotype calling, as their indel and homopolymer
+// errors are mostly not counted as mismatches at invariant positions.
+const longReadErrorRate = 0.05
+
+// ErrorModel counts aligned bases and mismatches at invariant positions of marker amplicons, in total,
+// by sequencing cycle and by base quality. Each count is a pair of bases and mismatches.
+type ErrorModel struct {
+	Bases, Mismatches uint64
+	Cycle             [][2]uint64
+	Quality           [][2]uint64
+
+	// floor is the minimum rate used in genotype calling, e.g. of long reads.
+	floor float64
+}
+
+// Rate returns the rate of mismatches per base.
+func (e *ErrorModel) Rate() float64 {
+	if e == nil || e.Bases == 0 {
+		return 0
+	}
+	return float64(e.Mismatches) / float64(e.Bases)
+}
+
+// add counts a base sequenced at the cycle with the quality.
+func (e *ErrorModel) add(cycle, quality int, mismatch bool) {
+	for len(e.Cycle) <= cycle {
+		e.Cycle = append(e.Cycle, [2]uint64{})
+	}
+	for len(e.Quality) <= quality {
+		e.Quality = append(e.Quality, [2]uint64{})
+	}
+	e.Bases++
+	e.Cycle[cycle][0]++
+	e.Quality[quality][0]++
+	if mismatch {
+		e.Mismatches++
+		e.Cycle[cycle][1]++
+		e.Quality[quality][1]++
+	}
+}
+
+// markerRegion is the span of a marker on a chromosome, 1-based inclusive.
+type markerRegion struct {
+	start, end int64
+}
+
+// errorSites are the spans of markers, and their positions excluded from the error model.
+type errorSites struct {
+	regions map[string][]markerRegion
+	sites   map[string]map[int64]bool
+}
+
+// newErrorSites gathers the spans and positions of markers, sorted by start on each chromosome.
+func newErrorSites(markers []GeneticMarker) *errorSites {
+	var (
+		regions = make(map[string][]markerRegion)
+		sites   = make(map[string]map[int64]bool)
+	)
+	for _, marker := range markers {
+		start, end := MarkerSpan(marker)
+		chrom := marker.GetCHROM()
+		regions[chrom] = append(regions[chrom], markerRegion{start, end})
+		if sites[chrom] == nil {
+			sites[chrom] = make(map[int64]bool)
+		}
+		sites[chrom][marker.GetPOS()] = true
+		switch m := marker.(type) {
+		case MH:
+			for _, offset := range m.OffSet {
+				sites[chrom][m.POS+int64(offset)] = true
+			}
+		case InDel:
+			for pos := m.POS; pos <= m.End(); pos++ {
+				sites[chrom][pos] = true
+			}
+		case STR:
+			for pos := m.POS; pos <= m.End; pos++ {
+				sites[chrom][pos] = true
+			}
+		}
+	}
+	for chrom := range regions {
+		sort.Slice(regions[chrom], func(i, j int) bool {
+			return regions[chrom][i].start < regions[chrom][j].start
+		})
+	}
+	return &errorSites{regions: regions, sites: sites}
+}
+
+// count estimates sequencing errors from mismatches (MD tag, or Options.Reference) of a read overlapping markers.
+// Positions of the markers, known SNPs given by Options.KnownSNPs and masked bases are not invariant, and are excluded.
+func (e *ErrorModel) count(sam *SAM, markers *errorSites, opt *Options) {
+	var (
+		end      = sam.alignedEnd()
+		overlaps bool
+	)
+	for _, region := range markers.regions[sam.chr] {
+		if region.start > end {
+			break
+		}
+		overlaps = overlaps || region.end >= sam.pos
+	}
+	if !overlaps {
+		return
+	}
+	mismatches := sam.MismatchArray(opt.Reference)
+	if mismatches == nil {
+		return
+	}
+	for i, q := range sam.RefToQuery() {
+		pos := sam.pos + int64(i)
+		if q < 0 || q >= int64(len(sam.seq)) || i >= len(mismatches) || sam.seq[q] == 'N' ||
+			markers.sites[sam.chr][pos] || opt.KnownSNPs[sam.chr][pos] {
+			continue
+		}
+		var cycle, quality = int(q), 0
+		if readStrand(sam) == Reverse {
+			cycle = len(sam.seq) - 1 - int(q)
+		}
+		if q < int64(len(sam.qual)) && sam.qual != "*" {
+			quality = max(0, int(sam.qual[q])-33)
+		}
+		e.add(cycle, quality, mismatches[i])
+	}
+}
+
+// binomialTail returns the probability of k or more successes in n trials of probability p.
+func binomialTail(k, n int, p float64) float64 {
+	if k <= 0 {
+		return 1
+	}
+	if k > n || p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return 1
+	}
+	var tail float64
+	for i := k; i <= n; i++ {
+		tail += math.Exp(logFactorial(n) - logFactorial(i) - logFactorial(n-i) +
+			float64(i)*math.Log(p) + float64(n-i)*math.Log(1-p))
+	}
+	return math.Min(1, tail)
+}
+
+// callRate returns the error rate used in genotype calling, at least the floor of the model, or 0 without model.
+func (e *ErrorModel) callRate() float64 {
+	if e == nil {
+		return 0
+	}
+	return math.Max(e.Rate(), e.floor)
+}
+
+// AboveNoise reports whether an allele with depth n of the marker's depth is unlikely to be made of sequencing
+// errors at its site, by a binomial test against the estimated error rate. It is always true without model.
+func (e *ErrorModel) AboveNoise(n, depth float64) bool {
+	rate := e.callRate()
+	return rate == 0 || binomialTail(int(math.Round(n)), int(math.Round(depth)), rate) < errorCallP
+}
+
+// HaplotypeAboveNoise reports whether a microhaplotype allele with depth n of the marker's depth is unlikely to be
+// made of sequencing errors in reads of another allele, differing at sites SNPs. Each error makes one of three other
+// bases, so that a read is turned into the allele with probability (rate/3)^sites. It is always true without model,
+// or with no site, e.g. for the deepest allele.
+func (e *ErrorModel) HaplotypeAboveNoise(n, depth float64, sites int) bool {
+	rate := e.callRate()
+	if rate == 0 || sites == 0 {
+		return true
+	}
+	p := math.Pow(rate/3, float64(sites))
+	return binomialTail(int(math.Round(n)), int(math.Round(depth)), p) < errorCallP
+}
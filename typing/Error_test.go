@@ -0,0 +1,25 @@
+package typing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBinomialTail(t *testing.T) {
+	var cases = []struct {
+		k, n int
+		p    float64
+		want float64
+	}{
+		{0, 10, 0.1, 1},
+		{11, 10, 0.1, 0},
+		{10, 10, 0.5, 1.0 / 1024},
+		{9, 10, 0.5, 11.0 / 1024},
+		{1, 3, 0.5, 7.0 / 8},
+	}
+	for _, c := range cases {
+		if got := binomialTail(c.k, c.n, c.p); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("P(X >= %d; %d, %g) = %g, want %g", c.k, c.n, c.p, got, c.want)
+		}
+	}
+}
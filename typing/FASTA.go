@@ -0,0 +1,260 @@
+package typing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FAIRecord is a line of samtools faidx index.
+// The format follows as: NAME	LENGTH	OFFSET	LINEBASES	LINEWIDTH
+type FAIRecord struct {
+	Name      string
+	Length    int64
+	Offset    int64
+	LineBases int64
+	LineWidth int64
+}
+
+// FASTA supports random access to sequences of an indexed FASTA file.
+type FASTA struct {
+	file  *os.File
+	Names []string
+	Index map[string]FAIRecord
+
+	// err is the first error reading sequences, see Err.
+	err error
+	// built is set for an index built by scanning the FASTA file, which is not saved yet.
+	built bool
+}
+
+// NewFASTA opens the index of a FASTA file from the .fai file next to it.
+// If the .fai file is absent, the index is built in memory by scanning the FASTA file, see SaveIndex.
+func NewFASTA(file *os.File) (*FASTA, error) {
+	var fasta = &FASTA{file: file, Index: make(map[string]FAIRecord)}
+
+	if fai, err := os.Open(file.Name() + ".fai"); err == nil {
+		defer fai.Close()
+		scanner := NewScanner(fai)
+		for scanner.Scan() {
+			fields := strings.Split(scanner.Text(), "\t")
+			if len(fields) < 5 {
+				continue
+			}
+			var (
+				record = FAIRecord{Name: fields[0]}
+				values [4]int64
+			)
+			for i := range values {
+				if values[i], err = strconv.ParseInt(fields[i+1], 10, 64); err != nil {
+					return nil, fmt.Errorf("%s.fai: %w", file.Name(), err)
+				}
+			}
+			record.Length, record.Offset, record.LineBases, record.LineWidth = values[0], values[1], values[2], values[3]
+			fasta.Names = append(fasta.Names, record.Name)
+			fasta.Index[record.Name] = record
+		}
+		return fasta, scanner.Err()
+	}
+
+	if err := fasta.buildIndex(); err != nil {
+		return nil, err
+	}
+	return fasta, nil
+}
+
+// SaveIndex writes the index built by NewFASTA to the .fai file next to the FASTA file, so that the FASTA file is not
+// scanned again next time. It does nothing for an index read from the .fai file.
+func (f *FASTA) SaveIndex() error {
+	if !f.built {
+		return nil
+	}
+	fai, err := os.Create(f.file.Name() + ".fai")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(fai)
+	for _, name := range f.Names {
+		r := f.Index[name]
+		if _, err = writer.WriteString(fmt.Sprintf("%s\t%d\t%d\t%d\t%d\n", r.Name, r.Length, r.Offset, r.LineBases, r.LineWidth)); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := fai.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		f.built = false
+	}
+	return err
+}
+
+// buildIndex scans the FASTA file and records the offset and line layout of each sequence.
+func (f *FASTA) buildIndex() error {
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var (
+		reader     = bufio.NewReader(f.file)
+		offset     int64
+		lineNumber int
+		current    *FAIRecord
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			offset += int64(len(line))
+			lineNumber++
+			if line[0] == '>' {
+				if current != nil {
+					f.Index[current.Name] = *current
+				}
+				name := strings.Fields(line[1:])
+				if len(name) == 0 {
+					return &ParseError{File: f.file.Name(), Line: lineNumber, Record: strings.TrimRight(line, "\r\n"),
+						Err: errors.New("empty sequence name")}
+				}
+				current = &FAIRecord{Name: name[0], Offset: offset}
+				f.Names = append(f.Names, current.Name)
+			} else if current != nil {
+				bases := int64(len(strings.TrimRight(line, "\r\n")))
+				if current.LineBases == 0 {
+					current.LineBases, current.LineWidth = bases, int64(len(line))
+				}
+				current.Length += bases
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if current != nil {
+		f.Index[current.Name] = *current
+	}
+	f.built = true
+	return nil
+}
+
+// Fetch returns the upper case sequence of chrom from start to end, both 1-based and inclusive.
+// The region is clipped to the sequence, and empty string is returned for unknown chrom, or a read error kept by Err.
+func (f *FASTA) Fetch(chrom string, start, end int64) string {
+	record, ok := f.Index[chrom]
+	if !ok || record.LineBases == 0 {
+		return ""
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end > record.Length {
+		end = record.Length
+	}
+	if start > end {
+		return ""
+	}
+
+	// Convert 0-based sequence coordinates to file offsets, skipping line endings.
+	fileOffset := func(i int64) int64 {
+		return record.Offset + i/record.LineBases*record.LineWidth + i%record.LineBases
+	}
+	var (
+		from = fileOffset(start - 1)
+		to   = fileOffset(end-1) + 1
+		buf  = make([]byte, to-from)
+	)
+	if _, err := f.file.ReadAt(buf, from); err != nil && err != io.EOF {
+		if f.err == nil {
+			f.err = err
+		}
+		return ""
+	}
+	return strings.ToUpper(strings.NewReplacer("\n", "", "\r", "").Replace(string(buf)))
+}
+
+// Err returns the first error reading sequences by Fetch, or nil. It is nil for no reference.
+func (f *FASTA) Err() error {
+	if f == nil {
+		return nil
+	}
+	return f.err
+}
+
+// Base returns the upper case base of chrom at 1-based pos, or empty string if out of the sequence.
+func (f *FASTA) Base(chrom string, pos int64) string {
+	return f.Fetch(chrom, pos, pos)
+}
+
+// Has reports whether chrom exists in the FASTA index.
+func (f *FASTA) Has(chrom string) bool {
+	_, ok := f.Index[chrom]
+	return ok
+}
+
+// ReferenceHaplotype derives the REF haplotype of a microhaplotype from POS and OffSet.
+func (f *FASTA) ReferenceHaplotype(mh MH) AlleleMH {
+	var bases = []string{f.Base(mh.CHROM, mh.POS)}
+	for _, offset := range mh.OffSet {
+		bases = append(bases, f.Base(mh.CHROM, mh.POS+int64(offset)))
+	}
+	return AlleleMH(strings.Join(bases, "-"))
+}
+
+// Flank returns n bases on the left and right sides of a region.
+func (f *FASTA) Flank(chrom string, start, end, n int64) (left, right string) {
+	return f.Fetch(chrom, start-n, start-1), f.Fetch(chrom, end+1, end+n)
+}
+
+// FillReference derives the REF alleles left as "." in the panel from the reference,
+// and returns warnings about REF alleles differing from the reference.
+func FillReference(markers []GeneticMarker, reference *FASTA) (warnings []error) {
+	for i, marker := range markers {
+		if !reference.Has(marker.GetCHROM()) {
+			warnings = append(warnings,
+				fmt.Errorf("%s: CHROM %s is not found in reference", marker.GetID(), marker.GetCHROM()))
+			continue
+		}
+		switch m := marker.(type) {
+		case SNP:
+			ref := reference.Base(m.CHROM, m.POS)
+			if m.REF == "." || m.REF == "" {
+				m.REF = ref
+			} else if !strings.EqualFold(m.REF, ref) {
+				warnings = append(warnings, fmt.Errorf("%s: REF %s differs from reference %s", m.ID, m.REF, ref))
+			}
+			markers[i] = m
+		case MH:
+			ref := reference.ReferenceHaplotype(m)
+			if m.REF == "." || m.REF == "" {
+				delete(m.Alleles, m.REF)
+				m.REF = ref
+				m.Alleles[ref] = 0
+			} else if !strings.EqualFold(m.REF, ref) {
+				warnings = append(warnings, fmt.Errorf("%s: REF %s differs from reference %s", m.ID, m.REF, ref))
+			}
+			markers[i] = m
+		case InDel:
+			if ref := reference.Fetch(m.CHROM, m.POS, m.End()); !strings.EqualFold(m.REF, ref) {
+				warnings = append(warnings, fmt.Errorf("%s: REF %s differs from reference %s", m.ID, m.REF, ref))
+			}
+		case STR:
+			ref := reference.Fetch(m.CHROM, m.POS, m.End)
+			if m.REF == "." || m.REF == "" {
+				m.REF = ref
+			} else if !strings.EqualFold(m.REF, ref) {
+				warnings = append(warnings, fmt.Errorf("%s: REF %s differs from reference %s", m.ID, m.REF, ref))
+			}
+			markers[i] = m
+		}
+	}
+	return warnings
+}
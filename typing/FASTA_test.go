@@ -0,0 +1,108 @@
+package typing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFASTAFetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ref.fa")
+	if err := os.WriteFile(path, []byte(">chr1 test\nACGTA\nCCGTT\nGG\n>chr2\nttttt\naaa\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handle, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+
+	fasta, err := NewFASTA(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		chrom      string
+		start, end int64
+		want       string
+	}{
+		{"chr1", 1, 5, "ACGTA"},
+		{"chr1", 4, 7, "TACC"},
+		{"chr1", 10, 20, "TGG"},
+		{"chr2", 5, 6, "TA"},
+		{"chr3", 1, 2, ""},
+	}
+	for _, c := range cases {
+		if got := fasta.Fetch(c.chrom, c.start, c.end); got != c.want {
+			t.Errorf("Fetch(%s, %d, %d) set to %q, want %q", c.chrom, c.start, c.end, got, c.want)
+		}
+	}
+
+	// The index is only saved by SaveIndex, and should be reloaded identically.
+	if _, err := os.Stat(path + ".fai"); !os.IsNotExist(err) {
+		t.Errorf("NewFASTA wrote the index: %v", err)
+	}
+	if err := fasta.SaveIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := NewFASTA(handle); err != nil {
+		t.Error(err)
+	} else if reloaded.Index["chr2"] != fasta.Index["chr2"] {
+		t.Errorf("reloaded index set to %v, want %v", reloaded.Index["chr2"], fasta.Index["chr2"])
+	}
+}
+
+func TestFASTAEmptyName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ref.fa")
+	if err := os.WriteFile(path, []byte(">chr1\nACGT\n> \nACGT\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handle, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+
+	var parseError *ParseError
+	if _, err = NewFASTA(handle); !errors.As(err, &parseError) || parseError.Line != 3 {
+		t.Errorf("empty sequence name: error %v, want a ParseError at line 3", err)
+	}
+}
+
+func TestFillReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ref.fa")
+	if err := os.WriteFile(path, []byte(">chr1\nACGTACGTAC\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handle, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+	reference, err := NewFASTA(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	markers, err := NewVCFFormat(strings.NewReader("chr1\t2\tmh\t.\tA-A\t.\tPASS\tOFFSET=2\n" +
+		"chr1\t3\tsnp\tA\tT\t.\tPASS\t.\n" +
+		"chr2\t3\tmissing\tA\tT\t.\tPASS\t.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := FillReference(markers, reference)
+	if mh := markers[0].(MH); mh.REF != "C-T" {
+		t.Errorf("REF filled as %s, want C-T", mh.REF)
+	}
+	var want = []string{"snp: REF A differs from reference G", "missing: CHROM chr2 is not found in reference"}
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings %v, want %d", warnings, len(want))
+	}
+	for i := range want {
+		if warnings[i].Error() != want[i] {
+			t.Errorf("warning %q, want %q", warnings[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package typing
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// FASTQRecord is a read of FASTQ format.
+type FASTQRecord struct {
+	Name string
+	Seq  string
+	Qual string
+}
+
+// FASTQReader reads plain or gzipped FASTQ files record by record.
+type FASTQReader struct {
+	reader *bufio.Reader
+	gzip   *gzip.Reader
+}
+
+// NewFASTQReader detects gzip compression by its magic number and returns a reader of the file.
+func NewFASTQReader(file io.Reader) (*FASTQReader, error) {
+	var (
+		buffered = bufio.NewReaderSize(file, 1024*1024)
+		reader   = &FASTQReader{reader: buffered}
+	)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		reader.gzip = gz
+		reader.reader = bufio.NewReaderSize(gz, 1024*1024)
+	}
+	return reader, nil
+}
+
+// readLine reads a whole line of any length without line ending.
+func (r *FASTQReader) readLine() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// Read returns the next record, or io.EOF at the end of file.
+func (r *FASTQReader) Read() (record FASTQRecord, err error) {
+	var header string
+	for header == "" {
+		if header, err = r.readLine(); err != nil {
+			return
+		}
+	}
+	var lines [3]string
+	for i := range lines {
+		if lines[i], err = r.readLine(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+	}
+	// The read name ends at the first white space, and the mate suffix /1 or /2 is removed.
+	name := strings.Fields(strings.TrimPrefix(header, "@"))
+	if len(name) > 0 {
+		record.Name = strings.TrimSuffix(strings.TrimSuffix(name[0], "/1"), "/2")
+	}
+	record.Seq, record.Qual = strings.ToUpper(lines[0]), lines[2]
+	return
+}
+
+// Close closes the gzip stream if any.
+func (r *FASTQReader) Close() error {
+	if r.gzip != nil {
+		return r.gzip.Close()
+	}
+	return nil
+}
+
+// reverseComplement returns the reverse complement of a DNA sequence.
+func reverseComplement(seq string) string {
+	var complement = map[byte]byte{'A': 'T', 'T': 'A', 'C': 'G', 'G': 'C', 'N': 'N'}
+	var rc = make([]byte, len(seq))
+	for i := 0; i < len(seq); i++ {
+		if c, ok := complement[seq[i]]; ok {
+			rc[len(seq)-1-i] = c
+		} else {
+			rc[len(seq)-1-i] = 'N'
+		}
+	}
+	return string(rc)
+}
+
+// reverseString returns the reverse of a string, e.g. base qualities of a reverse complemented read.
+func reverseString(s string) string {
+	var r = make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		r[len(s)-1-i] = s[i]
+	}
+	return string(r)
+}
@@ -0,0 +1,196 @@
+package typing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InDel is a marker whose REF or ALT is longer than one base: an insertion, a deletion (e.g. a DIP marker),
+// or a multi-nucleotide polymorphism (MNP). Alleles are full sequences of REF and ALT, as written in VCF.
+type InDel struct {
+	VCFFormat
+
+	Alleles     map[string]float64 // REF and each ALT.
+	RareAlleles map[string]float64 // other sequences observed at the locus.
+	Strand      map[string][2]float64
+}
+
+// Type assertion at compile time, to check InDel implements GeneticMarker interface.
+var _ GeneticMarker = (*InDel)(nil)
+
+// isInDel reports whether a VCF record of a single marker is an indel or MNP rather than an SNP.
+func isInDel(record VCFFormat) bool {
+	if len(record.REF) > 1 {
+		return true
+	}
+	for _, allele := range strings.Split(record.ALT, ",") {
+		if len(allele) > 1 && !strings.HasPrefix(allele, "<") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewInDel builds an InDel from a VCF record.
+func NewInDel(record VCFFormat) InDel {
+	var alleles = map[string]float64{strings.ToUpper(record.REF): 0}
+	for _, allele := range strings.Split(record.ALT, ",") {
+		if allele != "." && !strings.HasPrefix(allele, "<") {
+			alleles[strings.ToUpper(allele)] = 0
+		}
+	}
+	return InDel{VCFFormat: record, Alleles: alleles, RareAlleles: make(map[string]float64),
+		Strand: make(map[string][2]float64)}
+}
+
+func (indel InDel) GetPOS() int64 {
+	return indel.POS
+}
+func (indel InDel) GetCHROM() string {
+	return indel.CHROM
+}
+func (indel InDel) GetID() string {
+	return indel.ID
+}
+
+// End returns the last reference position of REF.
+func (indel InDel) End() int64 {
+	return indel.POS + int64(len(indel.REF)) - 1
+}
+
+// Kind is MNP for alleles of the same length, or else INDEL.
+func (indel InDel) Kind() string {
+	for allele := range indel.Alleles {
+		if len(allele) != len(indel.REF) {
+			return "INDEL"
+		}
+	}
+	return "MNP"
+}
+
+func (indel InDel) AlleleDepth() map[string]float64 {
+	var depth = make(map[string]float64, len(indel.Alleles))
+	for allele, n := range indel.Alleles {
+		depth[allele] = n
+	}
+	return depth
+}
+
+// StrandCount returns forward and reverse counts of each allele, including rare alleles.
+func (indel InDel) StrandCount() map[string][2]float64 {
+	return indel.Strand
+}
+
+func (indel InDel) String() string {
+	return genotypeString(indel.ID, indel.DetermineGenotype(), indel.Ploidy())
+}
+
+// VerboseString prints the depth of each allele and rare allele, in the same columns as microhaplotypes.
+func (indel InDel) VerboseString() string {
+	var depth float64
+	for _, n := range indel.Alleles {
+		depth += n
+	}
+	for _, n := range indel.RareAlleles {
+		depth += n
+	}
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s", indel.CHROM, indel.POS, indel.ID,
+		mapToString(indel.Alleles, depth, indel.opts()), mapToString(indel.RareAlleles, depth, indel.opts()),
+		strandToString(indel.Strand))
+}
+
+// DetermineGenotype keeps alleles more frequent than -min_freq, without strand bias, and not explained by
+// sequencing errors with Options.ErrorCall.
+func (indel InDel) DetermineGenotype() []string {
+	var (
+		count    float64
+		genotype []string
+		opt      = indel.opts()
+	)
+	for _, n := range indel.Alleles {
+		count += n
+	}
+	for allele, n := range indel.Alleles {
+		if n/count > opt.MinFreq && !opt.strandBiased(indel.Strand, allele) && opt.errorModel.AboveNoise(n, count) {
+			genotype = append(genotype, allele)
+		}
+	}
+	return callGenotype(genotype, indel.Alleles, indel.Ploidy())
+}
+
+// TypingInDel returns the read sequence aligned to REF of the marker, or empty string, see spanSequence.
+func (s *SAM) TypingInDel(indel InDel) string {
+	return s.spanSequence(indel.CHROM, indel.POS, indel.End(), indel.opts())
+}
+
+// spanSequence returns the read sequence aligned from start to end (1-based) of chrom, including inserted and
+// without deleted bases. The read must have aligned bases on both sides of the region, so that indels at its ends
+// are seen. An indel crossing the sides of the region, e.g. placed elsewhere in a repeat by the aligner, moves the
+// sides outward, and the extra bases are trimmed if they agree with Options.Reference.
+// The read must still have aligned bases outside the moved sides.
+// Empty string is returned if the read doesn't span the region, has masked bases in it, or has no SEQ.
+func (s *SAM) spanSequence(chrom string, start, end int64, opt *Options) string {
+	var (
+		left, right = start - 1, end + 1 // anchor bases.
+		refToQuery  = s.RefToQuery()
+	)
+	if s.chr != chrom || left < s.pos || right-s.pos >= int64(len(refToQuery)) {
+		return ""
+	}
+	if opt.Primers != nil && !opt.Primers.Covers(s, chrom, left, right) {
+		return "" // the read comes from another amplicon.
+	}
+	// Anchors move outward while they are deleted, or an indel is next to them on the outer side.
+	var (
+		first, last = left, right
+		query       = func(pos int64) int64 { return refToQuery[pos-s.pos] }
+	)
+	for first >= s.pos && (query(first) < 0 || first > s.pos && query(first-1) != query(first)-1) {
+		first--
+	}
+	for last-s.pos < int64(len(refToQuery)) &&
+		(query(last) < 0 || last+1-s.pos < int64(len(refToQuery)) && query(last+1) != query(last)+1) {
+		last++
+	}
+	if first < s.pos || last-s.pos >= int64(len(refToQuery)) || s.seq == "*" || query(last) > int64(len(s.seq)) {
+		return "" // SEQ may be "*" in e.g. secondary alignments.
+	}
+	sequence := strings.ToUpper(s.seq[query(first)+1 : query(last)])
+	if first != left || last != right {
+		if opt.Reference == nil || !opt.Reference.Has(chrom) {
+			return ""
+		}
+		var (
+			prefix = opt.Reference.Fetch(chrom, first+1, left)
+			suffix = opt.Reference.Fetch(chrom, right, last-1)
+		)
+		if len(sequence) < len(prefix)+len(suffix) || !strings.HasPrefix(sequence, prefix) || !strings.HasSuffix(sequence, suffix) {
+			return ""
+		}
+		sequence = sequence[len(prefix) : len(sequence)-len(suffix)]
+	}
+	if sequence == "" || strings.Contains(sequence, "N") {
+		return ""
+	}
+	return sequence
+}
+
+// count adds the allele of a read to the indel, or to rare alleles.
+func (indel *InDel) count(SAMrecord *SAM) {
+	allele := SAMrecord.TypingInDel(*indel)
+	if allele == "" {
+		return
+	}
+	if _, ok := indel.Alleles[allele]; ok {
+		indel.Alleles[allele]++
+	} else {
+		indel.RareAlleles[allele]++
+	}
+	count := indel.Strand[allele]
+	count[readStrand(SAMrecord)]++
+	indel.Strand[allele] = count
+}
+
+func (indel *InDel) typed() GeneticMarker {
+	return *indel
+}
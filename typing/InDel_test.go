@@ -0,0 +1,28 @@
+package typing
+
+import "testing"
+
+func TestTypingInDel(t *testing.T) {
+	var (
+		indel = NewInDel(VCFFormat{CHROM: "chr1", POS: 5, ID: "dip", REF: "GTT", ALT: "G"})
+		cases = []struct {
+			cigar, seq string
+			want       string
+		}{
+			{"10M", "ACGAGTTCAG", "GTT"},
+			{"5M2D3M", "ACGAGCAG", "G"},
+			{"5M1I5M", "ACGAGATTCAG", "GATT"},
+			{"6M", "ACGAGT", ""},        // doesn't span the right anchor.
+			{"10M", "ACGANTTCAG", ""},   // masked base.
+			{"4M2D4M", "ACGATCAG", "T"}, // deletion inside REF, making another allele.
+			{"3M2D5M", "ACGTTCAG", ""},  // deletion crosses the anchor, without -FASTA.
+			{"10M", "*", ""},            // no SEQ, e.g. a secondary alignment.
+		}
+	)
+	for _, c := range cases {
+		sam := &SAM{chr: "chr1", pos: 1, cigar: c.cigar, seq: c.seq}
+		if got := sam.TypingInDel(indel); got != c.want {
+			t.Errorf("%s %s typed as %q, want %q", c.cigar, c.seq, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,616 @@
+package typing
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AlleleMH = string
+
+type MH struct {
+	VCFFormat
+
+	// A microhaplotype marker consists of two and more SNP sites.
+	// The position of first SNP records in VCFFormat.POS, and others record in here.
+	// the offset is relative to first SNP.
+	OffSet []uint64
+
+	Alleles     map[AlleleMH]float64 // 单个个体每个基因型的统计深度
+	RareAlleles map[AlleleMH]float64
+	Strand      map[AlleleMH][2]float64 // 每个等位基因(含罕见)支持reads的正反链数目
+	Population  map[string][]AlleleMH   //每个个体的基因型(长度为倍性), 带"."的alleleMH都用单个"."表示
+
+	Reads        uint64 // reads or read pairs overlapping the microhaplotype after filtering.
+	PartialReads uint64 // reads missing some SNPs, i.e. containing ".".
+	Conflicts    uint64 // read pairs whose mates disagree on some SNPs.
+
+	// Fragments counts reads (or read pairs) of each observed, possibly partial, allele on each strand.
+	Fragments map[AlleleMH][2]float64
+	// AlleleSD is the standard deviation of expected depth of each allele due to ambiguous partial reads.
+	AlleleSD map[AlleleMH]float64
+
+	// Filter counts reads processed by the off-target mismatch policy, shared by copies of the marker.
+	Filter *MismatchFilter
+
+	// mates of read pairs wait here for each other, and are joined into one fragment.
+	mates map[string]mate
+
+	// realign holds the allele sequences reads are aligned to with Options.Realign, and likelihoods the reads.
+	realign     *alleleSequences
+	likelihoods []readLikelihood
+}
+
+func (mh MH) String() string {
+	return genotypeString(mh.ID, mh.DetermineGenotype(), mh.Ploidy())
+}
+
+// VerboseString print the details of each alleles of each markers, including coverage/count/depth, position.
+func (mh MH) VerboseString() string {
+	var depth float64
+	// the 'perc' option print the percent of each allele omitting the '%' symbol.
+	for _, k := range mh.RareAlleles {
+		depth += k
+	}
+	for _, k := range mh.Alleles {
+		depth += k
+	}
+
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s\t%s", mh.CHROM, mh.POS, mh.ID,
+		mapToString(mh.Alleles, depth, mh.opts()), mapToString(mh.RareAlleles, depth, mh.opts()),
+		strandToString(mh.Strand), sdToString(mh.AlleleSD))
+}
+
+// mapToString is generic function to print map type, in percentage with Options.Percent.
+func mapToString[T int | float64 | float32](m map[string]T, depth float64, opt *Options) string {
+	var (
+		s                = strings.Builder{}
+		mapSortedByValue = make([][2]interface{}, 0, len(m))
+	)
+	for k, v := range m {
+		mapSortedByValue = append(mapSortedByValue, [2]interface{}{k, v})
+	}
+	sort.Slice(mapSortedByValue, func(i, j int) bool {
+		return mapSortedByValue[i][1].(T) > mapSortedByValue[j][1].(T)
+	})
+	for _, v := range mapSortedByValue {
+		if depth > 0 && opt.Percent {
+			if p := v[1].(float64) / depth * 100; p >= opt.MinPercent {
+				s.WriteString(fmt.Sprintf("%s:%0.2f ", v[0].(string), p))
+			}
+		} else if depth == 0 {
+			s.WriteString("")
+		} else {
+			s.WriteString(fmt.Sprintf("%s:%0.f ", v[0].(string), v[1].(float64)))
+		}
+	}
+	return s.String()
+}
+
+func (mh MH) GetCHROM() string {
+	return mh.CHROM
+}
+func (mh MH) GetPOS() int64 {
+	return mh.POS
+}
+func (mh MH) GetID() string {
+	return mh.ID
+}
+
+// AlleleDepth returns the depth of known alleles only, rare alleles are not taken into account.
+func (mh MH) AlleleDepth() map[string]float64 {
+	var depth = make(map[string]float64, len(mh.Alleles))
+	for allele, n := range mh.Alleles {
+		depth[allele] = n
+	}
+	return depth
+}
+
+// DetermineGenotype return genotype.
+func (mh MH) DetermineGenotype() []AlleleMH {
+	var (
+		count    float64
+		genotype []AlleleMH
+		opt      = mh.opts()
+	)
+	// Calculate depth.
+	for _, n := range mh.Alleles {
+		count += n
+	}
+	// Omit alleles which of frequency are less than 3%, having strand bias, or explained by sequencing errors.
+	for allele, n := range mh.Alleles {
+		if n/count > opt.MinFreq && !opt.strandBiased(mh.Strand, allele) &&
+			opt.errorModel.HaplotypeAboveNoise(n, count, mh.noiseSites(allele)) {
+			genotype = append(genotype, allele)
+		}
+	}
+
+	// According to the number of retained alleles and the ploidy, the genotype or failure would be determined.
+	return callGenotype(genotype, mh.Alleles, mh.Ploidy())
+}
+
+// noiseSites returns the fewest SNPs at which the allele differs from another allele at least as deep, i.e. the
+// errors turning a read of that allele into this one, or 0 for the deepest allele.
+func (mh MH) noiseSites(allele AlleleMH) int {
+	var sites = -1
+	for other, n := range mh.Alleles {
+		if other == allele || n == 0 || n < mh.Alleles[allele] || len(other) != len(allele) {
+			continue
+		}
+		var differing int
+		for i := 0; i < len(allele); i += 2 {
+			if allele[i] != other[i] && allele[i] != '.' && other[i] != '.' {
+				differing++
+			}
+		}
+		if sites < 0 || differing < sites {
+			sites = differing
+		}
+	}
+	return max(sites, 0)
+}
+
+func (mh *MH) IndividualGenotype(sample string) []AlleleMH {
+	if genotype, ok := mh.Population[sample]; ok {
+		return genotype
+	} else {
+		var missing = make([]AlleleMH, mh.Ploidy())
+		for i := range missing {
+			missing[i] = "."
+		}
+		return missing
+	}
+}
+
+// 群体多态信息含量
+func (mh *MH) PIC() float64 {
+	var (
+		stat  = mh.allelePopulation()
+		numP  float64 // copies of all individuals, as many as their ploidy.
+		total int
+		freq  []float64
+	)
+	for _, genotype := range mh.Population {
+		numP += float64(len(genotype))
+	}
+	for _, count := range stat {
+		freq = append(freq, float64(count)/numP)
+		total += count
+	}
+	if float64(total) < numP {
+		freq = append(freq, (numP-float64(total))/numP)
+	}
+
+	return PIC(freq)
+}
+
+// PIC returns the polymorphism information content of alleles of the frequencies.
+func PIC(n []float64) float64 {
+	// PIC = 1 - \sum_{i=1}^{n} p_i^2 - (\sum_{i=1}^{n} p_i^2)^2 + \sum_{i=1}^{n} p_i^4
+	var sumOfSquares, sumOfBiquadratic float64
+	for _, v := range n {
+		sumOfSquares += math.Pow(v, 2)
+		sumOfBiquadratic += math.Pow(v, 4)
+	}
+	return 1 - sumOfSquares - math.Pow(sumOfSquares, 2) + sumOfBiquadratic
+}
+
+// EffectiveAlleles returns the effective number of alleles of the frequencies, the reciprocal of homozygosity.
+func EffectiveAlleles(n []float64) float64 {
+	var sumOfSquares float64
+	for _, v := range n {
+		sumOfSquares += v * v
+	}
+	if sumOfSquares == 0 {
+		return 0
+	}
+	return 1 / sumOfSquares
+}
+
+// 群体等位基因数量统计
+func (mh *MH) allelePopulation() map[AlleleMH]int {
+	var stat = make(map[AlleleMH]int)
+	for _, genotype := range mh.Population {
+		// 每条染色体
+		for _, allele := range genotype {
+			if allele != "." {
+				stat[allele]++
+			}
+		}
+	}
+
+	return stat
+}
+
+// Ae refers to the effective number of alleles
+func (mh *MH) Ae() int {
+	return len(mh.allelePopulation())
+}
+
+//func (mh *MHMarker) appendSNP(n uint64) {
+//	mh.SNPs = append(mh.SNPs, n)
+//}
+
+func (mh *MH) SimpleString() string {
+	var (
+		s       string
+		alleles []AlleleMH
+	)
+
+	for allele := range mh.Alleles {
+		alleles = append(alleles, allele)
+	}
+
+	switch len(alleles) {
+	case 0:
+		s = fmt.Sprintf("%s\t%s\t%s", mh.ID, ".", ".") // missing alignment
+	case 1:
+		s = fmt.Sprintf("%s\t%s\t%s", mh.ID, alleles[0], alleles[0])
+	case 2:
+		s = fmt.Sprintf("%s\t%s\t%s", mh.ID, alleles[0], alleles[1])
+	default:
+		s = fmt.Sprintf("#%s\t%v", mh.ID, mh.Alleles)
+	}
+	return s
+}
+
+// Mutation reports whether the read should be rejected for mismatches inside the microhaplotype body other than
+// the marker positions. Mismatches are taken from the MD tag, or computed against the reference when the aligner
+// did not emit it, and only substituted read bases count, not deletions or bases masked to N. Mismatches at known
+// population SNPs (Options.KnownSNPs) and at low quality bases (Options.MinBaseQual) are ignored, and up to
+// Options.MaxMismatch mismatches are tolerated.
+func (mh *MH) Mutation(sam *SAM) bool {
+	var (
+		opt     = mh.opts()
+		MDArray = sam.MismatchArray(opt.Reference)
+	)
+	if MDArray == nil {
+		return false
+	}
+
+	// Convert marker, only in MH body
+	var MKArray MutationArray = make([]bool, mh.OffSet[len(mh.OffSet)-1]+1)
+	MKArray[0] = true
+	for _, i := range mh.OffSet {
+		MKArray[i] = true
+	}
+
+	// compare
+	var (
+		refToQuery                  = sam.RefToQuery()
+		mismatch, known, lowQuality int
+	)
+	for i := 0; i < len(MKArray); i++ {
+		j := int(mh.POS) + i - int(sam.pos)
+		if j < 0 || j >= len(MDArray) || j >= len(refToQuery) || MKArray[i] || !MDArray[j] {
+			continue
+		}
+		// Deleted reference bases are marked in MDArray too, but only substitutions of read bases count, and bases
+		// masked to N, e.g. under primers, are not seen.
+		q := refToQuery[j]
+		if q < 0 || q < int64(len(sam.seq)) && sam.seq[q] == 'N' {
+			continue
+		}
+		mismatch++
+		if opt.KnownSNPs[mh.CHROM][mh.POS+int64(i)] {
+			known++
+		} else if sam.qual != "*" && q < int64(len(sam.qual)) && int(sam.qual[q])-33 < opt.MinBaseQual {
+			lowQuality++
+		}
+	}
+	return mh.Filter.Count(mismatch, known, lowQuality, opt.MaxMismatch)
+}
+
+// MutationArray marks mismatched reference positions of an alignment, starting from the leftmost mapping position.
+type MutationArray []bool
+
+// parseMD converts the MD tag into a MutationArray. Mismatched bases (e.g. "10A5") and deleted reference bases
+// (e.g. "10^AC5") are both marked as true.
+func parseMD(MD string) MutationArray {
+	var MDArray MutationArray
+	for i := 0; i < len(MD); {
+		switch c := MD[i]; {
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(MD) && MD[j] >= '0' && MD[j] <= '9' {
+				j++
+			}
+			match, _ := strconv.ParseInt(MD[i:j], 10, 64)
+			MDArray = append(MDArray, make([]bool, match)...)
+			i = j
+		case c == '^':
+			i++
+			for i < len(MD) && (MD[i] < '0' || MD[i] > '9') {
+				MDArray = append(MDArray, true)
+				i++
+			}
+		default:
+			MDArray = append(MDArray, true)
+			i++
+		}
+	}
+	return MDArray
+}
+
+func (ma MutationArray) String() string {
+	var s = strings.Builder{}
+	for i := 0; i < len(ma); i++ {
+		if ma[i] == false {
+			s.WriteString("0")
+		} else {
+			s.WriteString("1")
+		}
+	}
+	return s.String()
+}
+
+func NewMHCollection() map[string]MH {
+	var set = make(map[string]MH, 0)
+	return set
+}
+
+// NewMH builds a microhaplotype from a VCF record and the offsets of SNPs relative to the first one.
+func NewMH(record VCFFormat, offset []uint64) MH {
+	// The keys of MH.Alleles were initially set by VCF Ref and Alt fields.
+	var alleles = map[AlleleMH]float64{record.REF: 0}
+	for _, allele := range strings.Split(record.ALT, ",") {
+		alleles[allele] = 0
+	}
+	return MH{VCFFormat: record, OffSet: offset, Alleles: alleles, RareAlleles: make(map[AlleleMH]float64),
+		Strand: make(map[AlleleMH][2]float64), Filter: new(MismatchFilter)}
+}
+
+// MHGroup assigns SNP records to a microhaplotype, either by region or by SNP ID.
+type MHGroup struct {
+	ID         string
+	CHROM      string
+	Start, End int64 // 0-based half-open region of a BED line.
+	SNPs       map[string]bool
+}
+
+func (g MHGroup) contains(snp SNP) bool {
+	if g.SNPs != nil {
+		return g.SNPs[snp.ID]
+	}
+	return snp.CHROM == g.CHROM && snp.POS > g.Start && snp.POS <= g.End
+}
+
+// NewMHGroups imports the assignment of SNPs to microhaplotypes, from either a BED file (CHROM START END MH_ID)
+// or a two-column TSV file (SNP_ID MH_ID).
+func NewMHGroups(file io.Reader) (groups []MHGroup, err error) {
+	var byID = make(map[string]int)
+	scanner := NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || fields[0] == "track" || fields[0] == "browser" {
+			continue
+		}
+		if len(fields) >= 4 {
+			start, errStart := strconv.ParseInt(fields[1], 10, 64)
+			end, errEnd := strconv.ParseInt(fields[2], 10, 64)
+			if errStart == nil && errEnd == nil {
+				groups = append(groups, MHGroup{ID: fields[3], CHROM: fields[0], Start: start, End: end})
+				continue
+			}
+		}
+		i, ok := byID[fields[1]]
+		if !ok {
+			i = len(groups)
+			byID[fields[1]] = i
+			groups = append(groups, MHGroup{ID: fields[1], SNPs: make(map[string]bool)})
+		}
+		groups[i].SNPs[fields[0]] = true
+	}
+	return groups, scanner.Err()
+}
+
+// singleSNPHaplotypes returns the haplotypes having the ALT base of one SNP and REF bases of all others, i.e. the
+// alleles attested by the SNP records alone.
+func singleSNPHaplotypes(snps []SNP) []AlleleMH {
+	var (
+		ref        = make([]BASE, len(snps))
+		haplotypes []AlleleMH
+	)
+	for i, snp := range snps {
+		ref[i] = snp.REF
+	}
+	for i, snp := range snps {
+		for _, base := range strings.Split(snp.ALT, ",") {
+			if base == "." || base == snp.REF {
+				continue
+			}
+			haplotype := append([]BASE{}, ref...)
+			haplotype[i] = base
+			haplotypes = append(haplotypes, strings.Join(haplotype, "-"))
+		}
+	}
+	return haplotypes
+}
+
+// GroupSNPs converts the SNP records assigned to a group into a microhaplotype. REF is made of REF bases of all SNPs,
+// and ALT of the haplotypes carrying one ALT base each, so that other combinations are reported as rare alleles
+// until known. SNPs not assigned to any group are kept unchanged, as is the only SNP of a group, which is returned as
+// a warning.
+func GroupSNPs(markers []GeneticMarker, groups []MHGroup) (grouped []GeneticMarker, warnings []error, err error) {
+	var (
+		members  = make([][]SNP, len(groups))
+		assigned = make(map[int]int) // index of the first member of a group in grouped markers.
+	)
+	for _, marker := range markers {
+		snp, ok := marker.(SNP)
+		if !ok {
+			grouped = append(grouped, marker)
+			continue
+		}
+		var inGroup bool
+		for i, g := range groups {
+			if g.contains(snp) {
+				members[i] = append(members[i], snp)
+				if _, ok := assigned[i]; !ok {
+					assigned[i] = len(grouped)
+					grouped = append(grouped, nil)
+				}
+				inGroup = true
+				break
+			}
+		}
+		if !inGroup {
+			grouped = append(grouped, marker)
+		}
+	}
+
+	for i, snps := range members {
+		if len(snps) == 0 {
+			continue
+		}
+		sort.Slice(snps, func(a, b int) bool { return snps[a].POS < snps[b].POS })
+		if len(snps) == 1 {
+			warnings = append(warnings, fmt.Errorf("%s: only one SNP %s is assigned, kept as SNP", groups[i].ID, snps[0].ID))
+			grouped[assigned[i]] = snps[0]
+			continue
+		}
+		var (
+			offset []uint64
+			ref    []string
+		)
+		for j, snp := range snps {
+			if snp.CHROM != snps[0].CHROM {
+				return nil, nil, fmt.Errorf("%s: SNPs %s and %s are on different chromosomes", groups[i].ID, snps[0].ID, snp.ID)
+			}
+			if j > 0 && snp.POS == snps[j-1].POS {
+				return nil, nil, fmt.Errorf("%s: SNPs %s and %s are at the same position", groups[i].ID, snps[j-1].ID, snp.ID)
+			}
+			if j > 0 {
+				offset = append(offset, uint64(snp.POS-snps[0].POS))
+			}
+			ref = append(ref, snp.REF)
+		}
+		record := snps[0].VCFFormat
+		record.ID, record.REF, record.INFO = groups[i].ID, strings.Join(ref, "-"), nil
+		record.ALT = strings.Join(singleSNPHaplotypes(snps), ",")
+		grouped[assigned[i]] = NewMH(record, offset)
+	}
+	return grouped, warnings, nil
+}
+
+//var (
+//	SAM1 = "" // path
+//	MH1  = MHMarker{ID: "mhGP01", Chr: "Chr1", SNPs: []uint64{16574710, 16574718, 16574732}}
+//)
+
+// count adds the allele of a read to the microhaplotype. Both mates of a read pair are counted as one fragment.
+func (mh *MH) count(SAMrecord *SAM) {
+	if mh.realign != nil {
+		mh.countRealigned(SAMrecord)
+		return
+	}
+	allele := SAMrecord.TypingMH(*mh)
+	if allele == "" {
+		return
+	}
+	strand := readStrand(SAMrecord)
+	if SAMrecord.flag&0x1 == 0 || SAMrecord.flag&0x900 != 0 { // single-end, secondary or supplementary.
+		mh.countAllele(allele, strand)
+		return
+	}
+	if mh.mates == nil {
+		mh.mates = make(map[string]mate)
+	}
+	other, ok := mh.mates[SAMrecord.seqID]
+	if !ok {
+		mh.mates[SAMrecord.seqID] = mate{allele: allele, strand: strand, first: SAMrecord.flag&0x40 != 0}
+		return
+	}
+	delete(mh.mates, SAMrecord.seqID)
+	joined, conflict := joinMates(other.allele, allele)
+	if conflict {
+		mh.Conflicts++
+	}
+	if !other.first { // strand of the fragment is the strand of read 1.
+		other.strand = strand
+	}
+	mh.countAllele(joined, other.strand)
+}
+
+// typed counts the mates whose partner doesn't overlap the microhaplotype, or is filtered, and resolves alleles.
+func (mh *MH) typed() GeneticMarker {
+	var names []string
+	for name := range mh.mates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if other := mh.mates[name]; mh.realign != nil {
+			mh.addLikelihood(other.likelihood, other.span, other.allele, other.strand)
+		} else {
+			mh.countAllele(other.allele, other.strand)
+		}
+	}
+	mh.mates = nil
+	if mh.realign != nil {
+		mh.resolveRealigned()
+	} else {
+		mh.ResolveAlleles()
+	}
+	return *mh
+}
+
+// mate is the allele of a paired read, or its likelihoods and novel allele with Options.Realign, waiting for its mate.
+type mate struct {
+	allele     AlleleMH
+	likelihood []float64
+	span       [2]int64
+	strand     int
+	first      bool // read 1 of the pair.
+}
+
+// joinMates joins alleles of both mates of a read pair, filling the SNPs missing in one mate by the other.
+// The SNPs on which mates disagree are set to "." and reported as conflict.
+func joinMates(a, b AlleleMH) (joined AlleleMH, conflict bool) {
+	if len(a) != len(b) {
+		return a, true
+	}
+	var bases = []byte(a)
+	for i := 0; i < len(bases); i += 2 {
+		switch {
+		case bases[i] == b[i] || b[i] == '.':
+		case bases[i] == '.':
+			bases[i] = b[i]
+		default:
+			bases[i], conflict = '.', true
+		}
+	}
+	return AlleleMH(bases), conflict
+}
+
+// countAllele records a fragment with the allele on the strand, resolved to full alleles by ResolveAlleles.
+func (mh *MH) countAllele(allele AlleleMH, strand int) {
+	mh.Reads++
+	if strings.Contains(allele, ".") {
+		mh.PartialReads++
+	}
+	if mh.Fragments == nil {
+		mh.Fragments = make(map[AlleleMH][2]float64)
+	}
+	count := mh.Fragments[allele]
+	count[strand]++
+	mh.Fragments[allele] = count
+}
+
+// StrandCount returns forward and reverse counts of each allele, including rare alleles.
+func (mh MH) StrandCount() map[string][2]float64 {
+	return mh.Strand
+}
+
+// addStrand counts a read supporting the allele on the strand.
+func (mh *MH) addStrand(allele AlleleMH, strand int) {
+	if mh.Strand == nil {
+		mh.Strand = make(map[AlleleMH][2]float64)
+	}
+	count := mh.Strand[allele]
+	count[strand]++
+	mh.Strand[allele] = count
+}
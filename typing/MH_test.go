@@ -0,0 +1,102 @@
+package typing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMD(t *testing.T) {
+	var cases = map[string]string{
+		"10":        "0000000000",
+		"3A2":       "000100",
+		"0C3^AG2T0": "100011001",
+		"2^T1":      "0010",
+	}
+	for MD, want := range cases {
+		if got := parseMD(MD).String(); got != want {
+			t.Errorf("%s set to %s, want %s", MD, got, want)
+		}
+	}
+}
+
+func TestJoinMates(t *testing.T) {
+	var cases = []struct {
+		a, b     AlleleMH
+		joined   AlleleMH
+		conflict bool
+	}{
+		{"A-T-.-.", ".-.-G-T", "A-T-G-T", false},
+		{"A-T-G-.", ".-T-G-T", "A-T-G-T", false},
+		{"A-T-G-.", ".-C-G-T", "A-.-G-T", true},
+		{".-.-.-.", ".-.-G-.", ".-.-G-.", false},
+	}
+	for _, c := range cases {
+		if joined, conflict := joinMates(c.a, c.b); joined != c.joined || conflict != c.conflict {
+			t.Errorf("%s and %s joined to %s %v, want %s %v", c.a, c.b, joined, conflict, c.joined, c.conflict)
+		}
+	}
+}
+
+func TestGroupSNPs(t *testing.T) {
+	const panel = "chr1\t100\trs1\tA\tG\t.\tPASS\t.\n" +
+		"chr1\t118\trs2\tC\tT,G\t.\tPASS\t.\n" +
+		"chr1\t130\trs3\tG\tA\t.\tPASS\t.\n" +
+		"chr1\t500\trs4\tT\tC\t.\tPASS\t.\n" +
+		"chr2\t10\trs5\tA\tC\t.\tPASS\t.\n" +
+		"chr1\t100\trs6\tA\tT\t.\tPASS\t.\n"
+	markers, err := NewVCFFormat(strings.NewReader(panel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// BED regions are 0-based: 99-130 holds rs1 to rs3, and rs5 alone in 0-20 is kept as SNP.
+	groups, err := NewMHGroups(strings.NewReader("track name=groups\nchr1\t99\t130\tmhA\nchr2\t0\t20\tmhB\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].ID != "mhA" || groups[0].Start != 99 || groups[1].CHROM != "chr2" {
+		t.Fatalf("BED groups %+v", groups)
+	}
+	grouped, warnings, err := GroupSNPs(markers[:5], groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, marker := range grouped {
+		ids = append(ids, marker.GetID())
+	}
+	if strings.Join(ids, ",") != "mhA,rs4,rs5" {
+		t.Fatalf("grouped markers %v, want mhA,rs4,rs5", ids)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0].Error(), "mhB: only one SNP rs5") {
+		t.Errorf("warnings %v, want rs5 alone in mhB", warnings)
+	}
+	mh := grouped[0].(MH)
+	// Only haplotypes with one ALT base are known, e.g. G-T-G is left to rare alleles.
+	if mh.REF != "A-C-G" || mh.ALT != "G-C-G,A-T-G,A-G-G,A-C-A" || len(mh.OffSet) != 2 || mh.OffSet[1] != 30 {
+		t.Errorf("mhA grouped as REF %s ALT %s OFFSET %v", mh.REF, mh.ALT, mh.OffSet)
+	}
+	if _, ok := mh.Alleles["G-T-G"]; ok {
+		t.Error("G-T-G of two ALT bases is a known allele")
+	}
+
+	// TSV assigns SNPs by ID.
+	if groups, err = NewMHGroups(strings.NewReader("rs1\tmhC\nrs3\tmhC\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || !groups[0].SNPs["rs1"] || !groups[0].SNPs["rs3"] {
+		t.Fatalf("TSV groups %+v", groups)
+	}
+	if grouped, _, err = GroupSNPs(markers[:5], groups); err != nil {
+		t.Fatal(err)
+	}
+	if mh, ok := grouped[0].(MH); !ok || mh.REF != "A-G" || mh.ALT != "G-G,A-A" || len(grouped) != 4 {
+		t.Errorf("mhC grouped as %v of %d markers", grouped[0], len(grouped))
+	}
+
+	// SNPs at the same position can not be one microhaplotype.
+	groups, _ = NewMHGroups(strings.NewReader("rs1\tmhD\nrs6\tmhD\n"))
+	if _, _, err = GroupSNPs(markers, groups); err == nil {
+		t.Error("SNPs at the same position grouped")
+	}
+}
@@ -0,0 +1,165 @@
+package typing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MergeOptions configures the merging of read pairs sequencing the same fragment.
+type MergeOptions struct {
+	MinOverlap  int     // minimum overlap (bp) of read pairs to be merged.
+	MaxMismatch float64 // maximum mismatch rate in the overlap of read pairs to be merged.
+}
+
+// MergeStat counts read pairs merged into a single fragment, or typed as separate mates, for each amplicon.
+type MergeStat struct {
+	Merged     []uint64
+	Unmerged   []uint64
+	Unassigned uint64 // pairs whose mates are both assigned to no amplicon.
+}
+
+// overlapMismatches counts mismatches of r1 against the reverse complemented r2 placed at shift of r1,
+// ignoring N, and returns the length of overlap.
+func overlapMismatches(r1, r2 string, shift int) (overlap, mismatches int) {
+	for i := max(0, shift); i < len(r1) && i-shift < len(r2); i++ {
+		overlap++
+		a, b := r1[i], r2[i-shift]
+		if a != b && a != 'N' && b != 'N' {
+			mismatches++
+		}
+	}
+	return
+}
+
+// mergePair merges a read pair into the fragment they both sequence, if the end of read 1 overlaps the reverse
+// complement of read 2 by at least minOverlap bases, and at most maxMismatchRate of the overlap mismatches.
+// The base of higher quality is taken at disagreements. A fragment shorter than the reads (read-through into
+// adapters) is trimmed to the overlap.
+func mergePair(read1, read2 FASTQRecord, minOverlap int, maxMismatchRate float64) (merged FASTQRecord, ok bool) {
+	var (
+		seq2  = reverseComplement(read2.Seq)
+		qual2 = reverseString(read2.Qual)
+		best  = -1
+		rate  float64
+		shift int
+	)
+	if len(read1.Qual) != len(read1.Seq) || len(qual2) != len(seq2) {
+		return
+	}
+	// Read 2 starts at shift of read 1, a negative shift means read-through.
+	for s := len(read1.Seq) - minOverlap; s >= -(len(seq2) - minOverlap); s-- {
+		overlap, mismatches := overlapMismatches(read1.Seq, seq2, s)
+		if overlap < minOverlap || float64(mismatches) > maxMismatchRate*float64(overlap) {
+			continue
+		}
+		if r := float64(mismatches) / float64(overlap); best == -1 || r < rate || (r == rate && overlap > best) {
+			best, rate, shift = overlap, r, s
+		}
+	}
+	if best == -1 {
+		return
+	}
+
+	var start, end = 0, max(len(read1.Seq), shift+len(seq2))
+	if shift < 0 {
+		start, end = 0, min(len(read1.Seq), shift+len(seq2))
+	}
+	var seq, qual = make([]byte, 0, end-start), make([]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		j := i - shift
+		switch {
+		case j < 0 || j >= len(seq2):
+			seq, qual = append(seq, read1.Seq[i]), append(qual, read1.Qual[i])
+		case i >= len(read1.Seq):
+			seq, qual = append(seq, seq2[j]), append(qual, qual2[j])
+		case read1.Seq[i] == seq2[j]:
+			seq, qual = append(seq, read1.Seq[i]), append(qual, max(read1.Qual[i], qual2[j]))
+		case seq2[j] == 'N' || (read1.Seq[i] != 'N' && read1.Qual[i] >= qual2[j]):
+			// Quality of a disagreement is the difference of both qualities, at least 2.
+			seq, qual = append(seq, read1.Seq[i]), append(qual, max('!'+2, read1.Qual[i]-qual2[j]+'!'))
+		default:
+			seq, qual = append(seq, seq2[j]), append(qual, max('!'+2, qual2[j]-read1.Qual[i]+'!'))
+		}
+	}
+	return FASTQRecord{Name: read1.Name, Seq: string(seq), Qual: string(qual)}, true
+}
+
+// alignPairs merges read pairs of two FASTQ files and aligns the fragments to amplicons. Pairs failing to merge
+// are aligned as separate mates flagged as read 1 (0x40) and read 2 (0x80).
+func alignPairs(index *AmpliconIndex, fastq1, fastq2 io.Reader, writer *bufio.Writer, merge MergeOptions) (*MergeStat, error) {
+	var (
+		stat = &MergeStat{
+			Merged:   make([]uint64, len(index.Amplicons)),
+			Unmerged: make([]uint64, len(index.Amplicons)),
+		}
+		byName = make(map[string]int)
+	)
+	reader1, err := NewFASTQReader(fastq1)
+	if err != nil {
+		return nil, err
+	}
+	reader2, err := NewFASTQReader(fastq2)
+	if err != nil {
+		return nil, err
+	}
+	for i, amplicon := range index.Amplicons {
+		byName[amplicon.Name] = i
+	}
+	for {
+		read1, err1 := reader1.Read()
+		read2, err2 := reader2.Read()
+		if err1 == io.EOF && err2 == io.EOF {
+			break
+		}
+		if err1 == io.EOF || err2 == io.EOF {
+			return nil, errors.New("FASTQ of read 1 and read 2 have different numbers of reads")
+		}
+		if err1 != nil {
+			return nil, err1
+		}
+		if err2 != nil {
+			return nil, err2
+		}
+		if read1.Name != read2.Name {
+			return nil, fmt.Errorf("mates are out of order in FASTQ of read 1 and read 2: %s and %s", read1.Name, read2.Name)
+		}
+
+		if fragment, ok := mergePair(read1, read2, merge.MinOverlap, merge.MaxMismatch); ok {
+			if sam, ok := index.Align(fragment); ok {
+				stat.Merged[byName[sam.AuxiliaryTag["YA"]]]++
+				if _, err := writer.WriteString(sam.String() + "\n"); err != nil {
+					return nil, err
+				}
+			} else {
+				stat.Unassigned++
+			}
+			continue
+		}
+
+		var amplicon = -1
+		for mate, read := range []FASTQRecord{read1, read2} {
+			sam, ok := index.Align(read)
+			if !ok {
+				continue
+			}
+			sam.flag |= 0x1 | 0x40<<mate
+			if amplicon == -1 {
+				amplicon = byName[sam.AuxiliaryTag["YA"]]
+			}
+			if _, err := writer.WriteString(sam.String() + "\n"); err != nil {
+				return nil, err
+			}
+		}
+		if amplicon == -1 {
+			stat.Unassigned++
+		} else {
+			stat.Unmerged[amplicon]++
+		}
+	}
+	if err := reader1.Close(); err != nil {
+		return nil, err
+	}
+	return stat, reader2.Close()
+}
@@ -0,0 +1,28 @@
+package typing
+
+import "testing"
+
+func TestMergePair(t *testing.T) {
+	const fragment = "ACGTTGCAAGGCTTACCGATAGGTCCATTGAC"
+	var tests = []struct {
+		read1, qual1 string
+		read2, qual2 string
+		seq, qual    string
+		ok           bool
+	}{
+		// Overlap of 12 bases.
+		{fragment[:22], "IIIIIIIIIIIIIIIIIIIIII", reverseComplement(fragment[10:]), "IIIIIIIIIIIIIIIIIIIIII", fragment, "IIIIIIIIIIIIIIIIIIIIIIIIIIIIIIII", true},
+		// A disagreement takes the base of read 2 with higher quality.
+		{fragment[:15] + "T" + fragment[16:22], "IIIIIIIIIIIIIII#IIIIII", reverseComplement(fragment[10:]), "IIIIIIIIIIIIIIIIIIIIII", fragment, "IIIIIIIIIIIIIIIGIIIIIIIIIIIIIIII", true},
+		// Read-through is trimmed to the fragment.
+		{fragment[6:] + "AGATCG", "IIIIIIIIIIIIIIIIIIIIIIIIIIIIIIII", reverseComplement(fragment[6:]) + "AGATCG", "IIIIIIIIIIIIIIIIIIIIIIIIIIIIIIII", fragment[6:], "IIIIIIIIIIIIIIIIIIIIIIIIII", true},
+		// No overlap.
+		{fragment[:16], "IIIIIIIIIIIIIIII", reverseComplement(fragment[16:]), "IIIIIIIIIIIIIIII", "", "", false},
+	}
+	for i, test := range tests {
+		merged, ok := mergePair(FASTQRecord{Seq: test.read1, Qual: test.qual1}, FASTQRecord{Seq: test.read2, Qual: test.qual2}, 10, 0.1)
+		if ok != test.ok || merged.Seq != test.seq || merged.Qual != test.qual {
+			t.Errorf("test %d: got %s %s %v, want %s %s %v", i, merged.Seq, merged.Qual, ok, test.seq, test.qual, test.ok)
+		}
+	}
+}
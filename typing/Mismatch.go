@@ -0,0 +1,65 @@
+package typing
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NewKnownSNPs imports positions of known SNPs from a VCF file, keyed by CHROM and POS. All positions covered by
+// REF are masked.
+func NewKnownSNPs(file io.Reader) (map[string]map[int64]bool, error) {
+	var positions = make(map[string]map[int64]bool)
+	scanner := NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 5 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pos, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid POS: %w", fields[2], err)
+		}
+		if _, ok := positions[fields[0]]; !ok {
+			positions[fields[0]] = make(map[int64]bool)
+		}
+		for i := range fields[3] {
+			positions[fields[0]][pos+int64(i)] = true
+		}
+	}
+	return positions, scanner.Err()
+}
+
+// MismatchFilter counts reads processed by the off-target mismatch policy of a microhaplotype.
+// Each read having non-marker mismatches is attributed to the first policy deciding its fate.
+type MismatchFilter struct {
+	Checked    uint64 // reads checked for non-marker mismatches
+	KnownSNP   uint64 // reads kept by masking known population SNPs
+	LowQuality uint64 // reads kept by ignoring mismatches at low quality bases
+	Tolerated  uint64 // reads kept with mismatches no more than Options.MaxMismatch
+	Rejected   uint64 // reads rejected for mismatches more than Options.MaxMismatch
+}
+
+// Count records a read having mismatch non-marker mismatches, of which known are at known SNPs and lowQuality
+// are at low quality bases, and reports whether it is rejected for more than maxMismatch mismatches.
+func (f *MismatchFilter) Count(mismatch, known, lowQuality, maxMismatch int) bool {
+	if f == nil {
+		return mismatch-known-lowQuality > maxMismatch
+	}
+	f.Checked++
+	switch {
+	case mismatch == 0:
+		return false
+	case mismatch-known-lowQuality > maxMismatch:
+		f.Rejected++
+		return true
+	case mismatch > maxMismatch && mismatch-known <= maxMismatch:
+		f.KnownSNP++
+	case mismatch > maxMismatch:
+		f.LowQuality++
+	default:
+		f.Tolerated++
+	}
+	return false
+}
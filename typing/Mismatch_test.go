@@ -0,0 +1,78 @@
+package typing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMismatchFilterCount(t *testing.T) {
+	var cases = []struct {
+		mismatch, known, lowQuality, maxMismatch int
+		rejected                                 bool
+		counted                                  MismatchFilter
+	}{
+		{0, 0, 0, 0, false, MismatchFilter{Checked: 1}},
+		{1, 0, 0, 0, true, MismatchFilter{Checked: 1, Rejected: 1}},
+		{1, 0, 0, 1, false, MismatchFilter{Checked: 1, Tolerated: 1}},
+		{2, 1, 0, 1, false, MismatchFilter{Checked: 1, KnownSNP: 1}},
+		{2, 0, 1, 1, false, MismatchFilter{Checked: 1, LowQuality: 1}},
+		{3, 1, 1, 0, true, MismatchFilter{Checked: 1, Rejected: 1}},
+		{2, 1, 1, 0, false, MismatchFilter{Checked: 1, LowQuality: 1}},
+	}
+	for _, c := range cases {
+		var filter MismatchFilter
+		if got := filter.Count(c.mismatch, c.known, c.lowQuality, c.maxMismatch); got != c.rejected || filter != c.counted {
+			t.Errorf("Count(%d, %d, %d, %d) = %v counting %+v, want %v counting %+v",
+				c.mismatch, c.known, c.lowQuality, c.maxMismatch, got, filter, c.rejected, c.counted)
+		}
+	}
+	// Without a filter, reads are still rejected.
+	if !(*MismatchFilter)(nil).Count(2, 1, 0, 0) {
+		t.Error("nil filter kept a read with a mismatch above the maximum")
+	}
+}
+
+func TestMutation(t *testing.T) {
+	var (
+		bases = strings.Repeat("A", 30)
+		qual  = strings.Repeat("I", 14) + "#" + strings.Repeat("I", 15) // base quality 2 at 15.
+		// Bases from 13 to 15 masked under a primer.
+		masked = bases[:12] + "NNN" + bases[15:]
+	)
+	var cases = []struct {
+		name        string
+		record      string
+		known       int64
+		minBaseQual int
+		maxMismatch int
+		rejected    bool
+	}{
+		// Mismatches at 13 and 15 inside the body of the microhaplotype at 10 and 20.
+		{"mismatches", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:12T1T15", 0, 0, 1, true},
+		{"tolerated", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:12T1T15", 0, 0, 2, false},
+		{"known SNP", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:12T1T15", 13, 0, 1, false},
+		{"low quality", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:12T1T15", 0, 20, 1, false},
+		{"both exempt", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:12T1T15", 13, 20, 0, false},
+		{"marker position", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:9T19T0", 0, 0, 0, false},
+		// A mismatch at the first base, without QUAL, is not taken as low quality.
+		{"no QUAL", "r\t0\tchr1\t13\t60\t15M\t*\t0\t0\t" + bases[:15] + "\t*\tMD:Z:0T14", 0, 20, 0, true},
+		{"masked", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + masked + "\t" + qual + "\tMD:Z:12T1T15", 0, 0, 0, false},
+		{"deletion", "r\t0\tchr1\t1\t60\t12M2D16M\t*\t0\t0\t" + bases[:28] + "\t*\tMD:Z:12^TT16", 0, 0, 0, false},
+		{"outside body", "r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + bases + "\t" + qual + "\tMD:Z:5T24", 0, 0, 0, false},
+	}
+	for _, c := range cases {
+		sam, err := NewSAM(c.record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options := DefaultOptions()
+		options.MaxMismatch, options.MinBaseQual = c.maxMismatch, c.minBaseQual
+		if c.known > 0 {
+			options.KnownSNPs = map[string]map[int64]bool{"chr1": {c.known: true}}
+		}
+		mh := NewMH(VCFFormat{CHROM: "chr1", POS: 10, ID: "mh", REF: "A-A", ALT: "C-C", options: &options}, []uint64{10})
+		if got := mh.Mutation(sam); got != c.rejected {
+			t.Errorf("%s: Mutation() = %v, want %v", c.name, got, c.rejected)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package typing
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// mixtureError is the probability that a read reports an allele other than its true one.
+	mixtureError = 0.01
+	// mixtureMaxAlleles limits the candidate alleles of each marker to the deepest ones.
+	mixtureMaxAlleles = 6
+	// MixtureTopN is the number of ranked genotype combinations reported for each marker.
+	MixtureTopN = 5
+)
+
+// Contributors holds a combination of major and minor contributor genotypes at one marker.
+type Contributors struct {
+	Major  [2]string
+	Minor  [2]string
+	LogLik float64
+	Weight float64
+}
+
+// MixtureMarker holds the ranked genotype combinations of a two-person mixture at one marker.
+type MixtureMarker struct {
+	ID           string
+	Combinations []Contributors
+}
+
+// genotypePairs enumerates all unordered genotypes made of the given alleles.
+func genotypePairs(alleles []string) (pairs [][2]string) {
+	for i := range alleles {
+		for j := i; j < len(alleles); j++ {
+			pairs = append(pairs, [2]string{alleles[i], alleles[j]})
+		}
+	}
+	return
+}
+
+// candidateAlleles returns the deepest alleles having depth, together with the alleles of a known genotype.
+func candidateAlleles(depth map[string]float64, known [2]string) []string {
+	var alleles []string
+	for allele, n := range depth {
+		if n > 0 {
+			alleles = append(alleles, allele)
+		}
+	}
+	sort.Slice(alleles, func(i, j int) bool {
+		if depth[alleles[i]] == depth[alleles[j]] {
+			return alleles[i] < alleles[j]
+		}
+		return depth[alleles[i]] > depth[alleles[j]]
+	})
+	if len(alleles) > mixtureMaxAlleles {
+		alleles = alleles[:mixtureMaxAlleles]
+	}
+	for _, allele := range known {
+		if allele == "" || allele == "." {
+			continue
+		}
+		var exist bool
+		for _, a := range alleles {
+			exist = exist || a == allele
+		}
+		if !exist {
+			alleles = append(alleles, allele)
+		}
+	}
+	sort.Strings(alleles)
+	return alleles
+}
+
+// mixtureLogLik computes the multinomial log-likelihood of allele depths given contributor genotypes,
+// where mx is the proportion of the minor contributor.
+func mixtureLogLik(depth map[string]float64, alleles []string, major, minor [2]string, mx float64) float64 {
+	var logLik float64
+	for _, allele := range alleles {
+		var q float64
+		for i := 0; i < 2; i++ {
+			if major[i] == allele {
+				q += (1 - mx) / 2
+			}
+			if minor[i] == allele {
+				q += mx / 2
+			}
+		}
+		p := (1-mixtureError)*q + mixtureError/float64(len(alleles))
+		logLik += depth[allele] * math.Log(p)
+	}
+	return logLik
+}
+
+// sameGenotype reports whether two genotypes share the same alleles regardless of order.
+func sameGenotype(a, b [2]string) bool {
+	return (a[0] == b[0] && a[1] == b[1]) || (a[0] == b[1] && a[1] == b[0])
+}
+
+// mixtureCombinations returns all contributor combinations of one marker with log-likelihood at mx.
+// If known genotype was given, only combinations including it as either contributor are retained.
+func mixtureCombinations(depth map[string]float64, known [2]string, mx float64) []Contributors {
+	var (
+		alleles      = candidateAlleles(depth, known)
+		pairs        = genotypePairs(alleles)
+		combinations []Contributors
+		conditioned  = known[0] != "" && known[0] != "." && known[1] != "" && known[1] != "."
+	)
+	for _, major := range pairs {
+		for _, minor := range pairs {
+			if conditioned && !sameGenotype(major, known) && !sameGenotype(minor, known) {
+				continue
+			}
+			combinations = append(combinations, Contributors{
+				Major:  major,
+				Minor:  minor,
+				LogLik: mixtureLogLik(depth, alleles, major, minor, mx),
+			})
+		}
+	}
+	return combinations
+}
+
+// logSumExp returns log(sum(exp(x))) in a numerically stable way.
+func logSumExp(x []float64) float64 {
+	var maximum = math.Inf(-1)
+	for _, v := range x {
+		maximum = math.Max(maximum, v)
+	}
+	if math.IsInf(maximum, -1) {
+		return maximum
+	}
+	var sum float64
+	for _, v := range x {
+		sum += math.Exp(v - maximum)
+	}
+	return maximum + math.Log(sum)
+}
+
+// DeconvolveMixture estimates the shared mixture proportion of the minor contributor by grid search,
+// then ranks the genotype combinations of each marker by their posterior weight. Only diploid markers are deconvolved.
+func DeconvolveMixture(markers []GeneticMarker, knownProfile map[string][2]string) (mx float64, result []MixtureMarker) {
+	var bestLogLik = math.Inf(-1)
+	for step := 1; step <= 50; step++ {
+		var (
+			proportion = float64(step) / 100
+			logLik     float64
+		)
+		for _, marker := range markers {
+			if marker.Ploidy() != 2 {
+				continue
+			}
+			combinations := mixtureCombinations(marker.AlleleDepth(), knownProfile[marker.GetID()], proportion)
+			if len(combinations) == 0 {
+				continue
+			}
+			var logLiks []float64
+			for _, c := range combinations {
+				logLiks = append(logLiks, c.LogLik)
+			}
+			logLik += logSumExp(logLiks) - math.Log(float64(len(combinations)))
+		}
+		if logLik > bestLogLik {
+			bestLogLik, mx = logLik, proportion
+		}
+	}
+
+	for _, marker := range markers {
+		if marker.Ploidy() != 2 {
+			continue
+		}
+		combinations := mixtureCombinations(marker.AlleleDepth(), knownProfile[marker.GetID()], mx)
+		var logLiks []float64
+		for _, c := range combinations {
+			logLiks = append(logLiks, c.LogLik)
+		}
+		total := logSumExp(logLiks)
+		for i := range combinations {
+			combinations[i].Weight = math.Exp(combinations[i].LogLik - total)
+		}
+		sort.SliceStable(combinations, func(i, j int) bool {
+			return combinations[i].Weight > combinations[j].Weight
+		})
+		result = append(result, MixtureMarker{ID: marker.GetID(), Combinations: combinations})
+	}
+	return
+}
+
+// ReadGenotypeTab imports the genotypes of a sample from the .tab output, one marker per line.
+func ReadGenotypeTab(file io.Reader) (map[string][2]string, error) {
+	var profile = make(map[string][2]string)
+	scanner := NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Text()) == 0 || scanner.Text()[0] == '#' {
+			continue
+		}
+		if fields := strings.Split(scanner.Text(), "\t"); len(fields) >= 3 {
+			profile[fields[0]] = [2]string{fields[1], fields[2]}
+		}
+	}
+	return profile, scanner.Err()
+}
@@ -0,0 +1,20 @@
+package typing
+
+import "testing"
+
+func TestDeconvolveMixture(t *testing.T) {
+	// A 70:30 mixture of major A/T and minor C/C, with heterozygous major at two loci.
+	var markers = []GeneticMarker{
+		SNP{VCFFormat: VCFFormat{ID: "rs1"}, Alleles: [4]uint64{350, 350, 300, 0}},
+		SNP{VCFFormat: VCFFormat{ID: "rs2"}, Alleles: [4]uint64{0, 700, 0, 300}},
+		SNP{VCFFormat: VCFFormat{ID: "rs3"}, Alleles: [4]uint64{500, 0, 500, 0}},
+	}
+	mx, result := DeconvolveMixture(markers, nil)
+	if mx < 0.25 || mx > 0.35 {
+		t.Errorf("mixture proportion set to %0.2f, want about 0.30", mx)
+	}
+	best := result[0].Combinations[0]
+	if !sameGenotype(best.Major, [2]string{"A", "T"}) || !sameGenotype(best.Minor, [2]string{"C", "C"}) {
+		t.Errorf("rs1 set to %v/%v", best.Major, best.Minor)
+	}
+}
@@ -0,0 +1,62 @@
+package typing
+
+// Options configures the typing of markers and the calling of genotypes. Start from DefaultOptions, as zero values
+// are not the defaults.
+type Options struct {
+	MinFreq     float64 // minimum frequency of each allele, ranging from 0 for high depth to 1 for low depth.
+	StrandBiasP float64 // p-value of strand bias test below which an allele is excluded from genotype, 0 to disable.
+	ErrorCall   bool    // exclude alleles explained by the sequencing error rate estimated from the sample.
+	Stutter     float64 // maximum ratio of an STR stutter product (n-1/n+1) to its parent allele.
+	Ploidy      int     // number of copies of autosomal markers without PLOIDY in the panel.
+	Sex         string  // male or female: X-linked markers are haploid in males, Y-linked markers absent in females.
+
+	MaxMismatch int // maximum number of non-marker mismatches in a microhaplotype read.
+	MinBaseQual int // minimum base quality of a non-marker mismatch to be counted.
+
+	Realign bool // type microhaplotypes by aligning reads to the sequence of each known allele, requires Reference.
+
+	LongReads   bool  // long noisy reads: SNPs are typed through indels of the alignment, with a noisier error model.
+	IndelWindow int64 // with LongReads, SNPs within this distance (bp) of an indel of the read are not typed.
+
+	PrimerTolerance int64 // maximum distance (bp) between read end and primer end.
+	DropOffTarget   bool  // drop reads matching no primer.
+
+	Lenient bool // skip malformed SAM records, returned in Result.Rejected, instead of failing.
+
+	MinDepth   float64 // depth of a marker below which the call is flagged LOW_DEPTH in QC.
+	MinBalance float64 // minimum ratio of minor to major allele depth of a heterozygote in QC.
+
+	Percent    bool    // print percentage of each allele in verbose output, omitting % symbol.
+	MinPercent float64 // minimum percentage of alleles printed with Percent, range 0 to 100.
+
+	Reference *FASTA                    // reference sequences, or nil.
+	Primers   *PrimerSet                // amplicon primers masked in reads, or nil.
+	KnownSNPs map[string]map[int64]bool // known population SNPs masked inside microhaplotypes, or nil.
+
+	// errorModel is the error model of the sample used in genotype calling with ErrorCall or LongReads, set by Typer.
+	errorModel *ErrorModel
+}
+
+// DefaultOptions returns the options used by the command line without flags.
+func DefaultOptions() Options {
+	return Options{
+		MinFreq:         0.03,
+		Stutter:         0.15,
+		Ploidy:          2,
+		IndelWindow:     3,
+		PrimerTolerance: 5,
+		MinDepth:        10,
+		MinBalance:      0.3,
+	}
+}
+
+// defaultOptions are the options of markers not typed by a Typer.
+var defaultOptions = DefaultOptions()
+
+// opts returns the options the marker is typed with.
+func (v VCFFormat) opts() *Options {
+	if v.options == nil {
+		return &defaultOptions
+	}
+	return v.options
+}
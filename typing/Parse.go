@@ -0,0 +1,43 @@
+package typing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ParseError is a malformed record of an input file, located by file name and line number.
+type ParseError struct {
+	File   string // name of the file, empty if the reader has no name.
+	Line   int    // line number, starting from one.
+	Record string // the malformed line.
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// readerName returns the name of a file reader, e.g. *os.File, or empty for other readers.
+func readerName(r any) string {
+	if file, ok := r.(interface{ Name() string }); ok {
+		return file.Name()
+	}
+	return ""
+}
+
+// NewScanner returns a line scanner without the 64 KB line limit of bufio.Scanner, as SAM records of long reads, or
+// VCF records of many samples, are longer.
+func NewScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), math.MaxInt)
+	return scanner
+}
@@ -0,0 +1,52 @@
+package typing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewSAM(t *testing.T) {
+	var cases = []struct {
+		record string
+		ok     bool
+	}{
+		{"", true},
+		{"@HD\tVN:1.6", true},
+		{"r0\t0\tchr1\t1\t60\t4M\t=\t1\t0\tACGT\tIIII\tMD:Z:4", true},
+		{"r0\t0\tchr1\t1\t60\t4M\t*\t*\t0\tACGT\t*\tXX\tMD:Z:4", true}, // * for PNEXT and QUAL, odd tag ignored.
+		{"r0\t0\tchr1", false},
+		{"r0\t0\tchr1\tx\t60\t4M\t=\t1\t0\tACGT\tIIII", false},
+		{"r0\t0\tchr1\t1\t256\t4M\t=\t1\t0\tACGT\tIIII", false},
+		{"r0\t0\tchr1\t1\t60\t4M\t=\t1\t0\tACGT\tIII", false},
+	}
+	for _, c := range cases {
+		if _, err := NewSAM(c.record); (err == nil) != c.ok {
+			t.Errorf("NewSAM(%q) error %v", c.record, err)
+		}
+	}
+}
+
+func TestNewVCFFormatLenient(t *testing.T) {
+	const panel = "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n" +
+		"chr1\t5\ts1\tA\tG\t.\tPASS\t.\n" +
+		"chr1\tx\ts2\tA\tG\t.\tPASS\t.\n" +
+		"chr1\t9\tmh1\t.\t.\t.\tPASS\tOFFSET=3,a\n" +
+		"chr1\t20\ts3\tC\tT\t.\tPASS\t.\n"
+
+	var parseErr *ParseError
+	if _, err := NewVCFFormat(strings.NewReader(panel)); !errors.As(err, &parseErr) || parseErr.Line != 3 {
+		t.Errorf("strict parsing gave error %v, want a parse error at line 3", err)
+	}
+
+	markers, rejected, err := NewVCFFormatLenient(strings.NewReader(panel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(markers) != 2 || markers[1].GetID() != "s3" {
+		t.Errorf("got %d markers, want s1 and s3", len(markers))
+	}
+	if len(rejected) != 2 || rejected[0].Line != 3 || rejected[1].Line != 4 {
+		t.Errorf("rejected %v, want lines 3 and 4", rejected)
+	}
+}
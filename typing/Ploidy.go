@@ -0,0 +1,124 @@
+package typing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chromosomeClass returns "X", "Y" or "M" for sex and mitochondrial chromosomes, named with or without "chr".
+func chromosomeClass(chrom string) string {
+	switch strings.ToUpper(strings.TrimPrefix(strings.TrimPrefix(chrom, "chr"), "CHR")) {
+	case "X":
+		return "X"
+	case "Y":
+		return "Y"
+	case "M", "MT":
+		return "M"
+	}
+	return ""
+}
+
+// Ploidy returns the number of copies of the marker in the sample. PLOIDY in INFO comes first, then mitochondrial
+// and Y-linked markers are haploid, and X-linked markers are haploid in males given by Options.Sex. Y-linked markers
+// have no copy in females. Other markers have the ploidy given by Options.Ploidy.
+func (v VCFFormat) Ploidy() int {
+	if value, ok := v.INFO["PLOIDY"]; ok && len(value) > 0 {
+		// PLOIDY is checked by NewVCFFormat.
+		if n, err := strconv.Atoi(fmt.Sprint(value[0])); err == nil && n >= 0 {
+			return n
+		}
+	}
+	var sample = strings.ToLower(v.opts().Sex)
+	switch chromosomeClass(v.CHROM) {
+	case "M":
+		return 1
+	case "Y":
+		if sample == "female" || sample == "f" {
+			return 0
+		}
+		return 1
+	case "X":
+		if sample == "male" || sample == "m" {
+			return 1
+		}
+	}
+	return v.opts().Ploidy
+}
+
+// callGenotype assigns the copies of a locus of the ploidy to the alleles passing the filters. A homozygote has all
+// copies of a single allele. For more than two copies, copies are given in proportion to the depth by the largest
+// remainder, keeping at least one copy of each allele. No call (nil) is made for no allele, or more alleles than copies.
+func callGenotype(alleles []string, depth map[string]float64, ploidy int) []string {
+	if len(alleles) == 0 || len(alleles) > ploidy {
+		return nil
+	}
+	var (
+		total     float64
+		copies    = make([]int, len(alleles))
+		remainder = make([]float64, len(alleles))
+		assigned  int
+	)
+	// Alleles are ordered by depth, then by name, so that ties are resolved the same way in every run.
+	alleles = append([]string(nil), alleles...)
+	sort.SliceStable(alleles, func(i, j int) bool {
+		if depth[alleles[i]] == depth[alleles[j]] {
+			return alleles[i] < alleles[j]
+		}
+		return depth[alleles[i]] > depth[alleles[j]]
+	})
+	for _, allele := range alleles {
+		total += depth[allele]
+	}
+	for i, allele := range alleles {
+		share := float64(ploidy) / float64(len(alleles))
+		if total > 0 {
+			share = depth[allele] / total * float64(ploidy)
+		}
+		copies[i], remainder[i] = int(share), share-math.Floor(share)
+		assigned += copies[i]
+	}
+	// Remaining copies go to the largest remainders, or to the deepest alleles.
+	var order = make([]int, len(alleles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainder[order[i]] > remainder[order[j]]
+	})
+	for i := 0; assigned < ploidy; i = (i + 1) % len(order) {
+		copies[order[i]]++
+		assigned++
+	}
+	// Every allele passing the filters keeps a copy, taken from the allele with most copies, the shallowest in a tie.
+	for i := range copies {
+		if copies[i] == 0 {
+			most := 0
+			for j := range copies {
+				if copies[j] >= copies[most] {
+					most = j
+				}
+			}
+			copies[most]--
+			copies[i]++
+		}
+	}
+
+	var genotype []string
+	for i, allele := range alleles {
+		for j := 0; j < copies[i]; j++ {
+			genotype = append(genotype, allele)
+		}
+	}
+	sort.Strings(genotype)
+	return genotype
+}
+
+// genotypeString formats the marker ID and one column per copy, empty for no call.
+func genotypeString(id string, genotype []string, ploidy int) string {
+	var columns = make([]string, ploidy)
+	copy(columns, genotype)
+	return strings.Join(append([]string{id}, columns...), "\t")
+}
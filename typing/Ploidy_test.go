@@ -0,0 +1,31 @@
+package typing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallGenotype(t *testing.T) {
+	var (
+		depth = map[string]float64{"A": 60, "C": 30, "G": 10}
+		cases = []struct {
+			alleles []string
+			ploidy  int
+			want    string
+		}{
+			{[]string{"A"}, 1, "A"},
+			{[]string{"A", "C"}, 1, ""},
+			{[]string{"A"}, 2, "A/A"},
+			{[]string{"A", "C"}, 2, "A/C"},
+			{[]string{"A", "C", "G"}, 2, ""},
+			{[]string{"A", "C"}, 4, "A/A/A/C"},
+			{[]string{"A", "C", "G"}, 4, "A/A/C/G"},
+			{[]string{"A"}, 0, ""},
+		}
+	)
+	for _, c := range cases {
+		if got := strings.Join(callGenotype(c.alleles, depth, c.ploidy), "/"); got != c.want {
+			t.Errorf("%v in ploidy %d called %s, want %s", c.alleles, c.ploidy, got, c.want)
+		}
+	}
+}
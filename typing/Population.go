@@ -0,0 +1,251 @@
+package typing
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// populationPseudocount is added to the count of each allele in the population, so that alleles unseen in the
+	// population keep a prior.
+	populationPseudocount = 1
+	// posteriorBaseError is the sequencing error rate of reads given an allele, without the error model of the sample.
+	posteriorBaseError = 0.01
+)
+
+// ReadPopulation imports the genotypes of a reference population from a table like data/genotype-data.tab: a header
+// line naming the sample column and one column per copy of each marker, then one sample per line. Empty genotypes
+// are missing ("."). It returns the genotypes of each sample by column name, including non-marker columns.
+func ReadPopulation(file io.Reader) (map[string]map[string][]AlleleMH, error) {
+	var (
+		population = make(map[string]map[string][]AlleleMH)
+		scanner    = NewScanner(file)
+		header     []string
+		line       int
+	)
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if header == nil {
+			header = fields
+			continue
+		}
+		if len(fields) != len(header) {
+			return nil, &ParseError{File: readerName(file), Line: line, Record: scanner.Text(),
+				Err: fmt.Errorf("%d fields, expected %d as in header", len(fields), len(header))}
+		}
+		for i := 1; i < len(fields); i++ {
+			if population[header[i]] == nil {
+				population[header[i]] = make(map[string][]AlleleMH)
+			}
+			allele := strings.TrimSpace(fields[i])
+			if allele == "" {
+				allele = "."
+			}
+			population[header[i]][fields[0]] = append(population[header[i]][fields[0]], allele)
+		}
+	}
+	return population, scanner.Err()
+}
+
+// FillPopulation sets the population genotypes of the microhaplotypes imported by ReadPopulation.
+func FillPopulation(markers []GeneticMarker, population map[string]map[string][]AlleleMH) {
+	for i, marker := range markers {
+		if mh, ok := marker.(MH); ok && population[mh.ID] != nil {
+			mh.Population = population[mh.ID]
+			markers[i] = mh
+		}
+	}
+}
+
+// AlleleFrequency returns the frequency of the known and population alleles in the population, with a
+// pseudocount for each allele. Nil is returned without population.
+func (mh *MH) AlleleFrequency() map[AlleleMH]float64 {
+	if len(mh.Population) == 0 {
+		return nil
+	}
+	var (
+		count = mh.allelePopulation()
+		total float64
+		freq  = make(map[AlleleMH]float64)
+	)
+	for allele := range mh.Alleles {
+		if _, ok := count[allele]; !ok {
+			count[allele] = 0
+		}
+	}
+	for allele, n := range count {
+		if len(allele) != 2*len(mh.OffSet)+1 || strings.Contains(allele, ".") {
+			continue // e.g. "." as REF, or a genotype of another marker definition.
+		}
+		freq[allele] = float64(n) + populationPseudocount
+		total += freq[allele]
+	}
+	for allele := range freq {
+		freq[allele] /= total
+	}
+	return freq
+}
+
+// GenotypePosterior is a genotype, one allele per copy in sorted order, with its posterior probability.
+type GenotypePosterior struct {
+	Genotype    []AlleleMH
+	Probability float64
+}
+
+// PosteriorGenotypes combines the reads of the microhaplotype with the allele frequencies of the population. Each
+// genotype of the ploidy has a Hardy-Weinberg prior, and each read the likelihood of its observed SNPs given an
+// allele of the genotype drawn in proportion to its copies. Reads typed by realignment keep their own likelihood
+// given each known allele instead. It returns the posterior of every genotype, most likely first, or nil without
+// population or copies.
+func (mh MH) PosteriorGenotypes() []GenotypePosterior {
+	var (
+		freq    = mh.AlleleFrequency()
+		ploidy  = mh.Ploidy()
+		alleles []AlleleMH
+	)
+	if len(freq) == 0 || ploidy == 0 {
+		return nil
+	}
+	for allele := range freq {
+		alleles = append(alleles, allele)
+	}
+	sort.Strings(alleles)
+
+	// Likelihood of the observed allele of each fragment given each allele.
+	var (
+		e     = mh.opts().errorModel.callRate()
+		reads []float64
+		given [][]float64
+	)
+	if e == 0 {
+		e = posteriorBaseError
+	}
+	observedGiven := func(o AlleleMH) []float64 {
+		likelihood := make([]float64, len(alleles))
+		for k, a := range alleles {
+			likelihood[k] = 1
+			for i := 0; i < len(o); i += 2 {
+				switch {
+				case o[i] == '.':
+				case o[i] == a[i]:
+					likelihood[k] *= 1 - e
+				default:
+					likelihood[k] *= e / 3
+				}
+			}
+		}
+		return likelihood
+	}
+	if mh.realign != nil {
+		// Alleles without a known sequence, e.g. only seen in the population, are given the likelihood of the
+		// least likely known allele, so that the read is no evidence for them.
+		var index = make(map[AlleleMH]int, len(mh.realign.alleles))
+		for i, allele := range mh.realign.alleles {
+			index[allele] = i
+		}
+		for _, read := range mh.likelihoods {
+			if read.novel != "" {
+				reads, given = append(reads, 1), append(given, observedGiven(read.novel))
+				continue
+			}
+			var top, bottom = math.Inf(-1), math.Inf(1)
+			for _, l := range read.likelihood {
+				if !math.IsInf(l, -1) {
+					top, bottom = math.Max(top, l), math.Min(bottom, l)
+				}
+			}
+			if math.IsInf(top, -1) {
+				continue // aligned to no allele within the band.
+			}
+			likelihood := make([]float64, len(alleles))
+			for k, a := range alleles {
+				if i, ok := index[a]; ok {
+					likelihood[k] = math.Exp(read.likelihood[i] - top)
+				} else {
+					likelihood[k] = math.Exp(bottom - top)
+				}
+			}
+			reads, given = append(reads, 1), append(given, likelihood)
+		}
+	} else {
+		var observed []AlleleMH
+		for allele, count := range mh.Fragments {
+			if count[Forward]+count[Reverse] > 0 && len(allele) == 2*len(mh.OffSet)+1 {
+				observed = append(observed, allele)
+			}
+		}
+		sort.Strings(observed) // summed in a fixed order, so that posteriors are reproducible.
+		for _, o := range observed {
+			reads = append(reads, mh.Fragments[o][Forward]+mh.Fragments[o][Reverse])
+			given = append(given, observedGiven(o))
+		}
+	}
+
+	var (
+		posteriors []GenotypePosterior
+		copies     = make([]int, len(alleles))
+		top        = math.Inf(-1)
+	)
+	// Genotypes are enumerated as the copies of each allele, adding up to the ploidy.
+	var enumerate func(k, left int)
+	enumerate = func(k, left int) {
+		if k == len(alleles)-1 {
+			copies[k] = left
+		} else {
+			for copies[k] = left; copies[k] >= 0; copies[k]-- {
+				enumerate(k+1, left-copies[k])
+			}
+			return
+		}
+		logP, _ := math.Lgamma(float64(ploidy) + 1)
+		var genotype []AlleleMH
+		for i, c := range copies {
+			lg, _ := math.Lgamma(float64(c) + 1)
+			logP += float64(c)*math.Log(freq[alleles[i]]) - lg
+			for j := 0; j < c; j++ {
+				genotype = append(genotype, alleles[i])
+			}
+		}
+		for r, likelihood := range given {
+			var p float64
+			for i, c := range copies {
+				p += float64(c) / float64(ploidy) * likelihood[i]
+			}
+			logP += reads[r] * math.Log(p)
+		}
+		top = math.Max(top, logP)
+		posteriors = append(posteriors, GenotypePosterior{Genotype: genotype, Probability: logP})
+	}
+	enumerate(0, ploidy)
+
+	var sum float64
+	for i := range posteriors {
+		posteriors[i].Probability = math.Exp(posteriors[i].Probability - top)
+		sum += posteriors[i].Probability
+	}
+	for i := range posteriors {
+		posteriors[i].Probability /= sum
+	}
+	sort.SliceStable(posteriors, func(i, j int) bool {
+		return posteriors[i].Probability > posteriors[j].Probability
+	})
+	return posteriors
+}
+
+// Dosage returns the expected number of copies of each allele over the genotype posteriors.
+func Dosage(posteriors []GenotypePosterior) map[AlleleMH]float64 {
+	var dosage = make(map[AlleleMH]float64)
+	for _, posterior := range posteriors {
+		for _, allele := range posterior.Genotype {
+			dosage[allele] += posterior.Probability
+		}
+	}
+	return dosage
+}
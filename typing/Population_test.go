@@ -0,0 +1,127 @@
+package typing
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPosteriorGenotypes(t *testing.T) {
+	// A-T in 16 copies and C-G in 2, with one sample missing.
+	var table strings.Builder
+	table.WriteString("Sample\tGender\tmh\tmh\n")
+	for i := 0; i < 8; i++ {
+		table.WriteString("s" + string(rune('a'+i)) + "\tF\tA-T\tA-T\n")
+	}
+	table.WriteString("si\tM\tC-G\tC-G\nsj\tF\t\t\n")
+	population, err := ReadPopulation(strings.NewReader(table.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := population["mh"]["sj"]; len(got) != 2 || got[0] != "." {
+		t.Errorf("missing genotype read as %v", got)
+	}
+
+	var markers = []GeneticMarker{NewMH(VCFFormat{CHROM: "chr1", POS: 10, ID: "mh", REF: "A-T", ALT: "C-G"}, []uint64{5})}
+	FillPopulation(markers, population)
+	mh := markers[0].(MH)
+
+	// Without reads, the posterior is the Hardy-Weinberg prior of frequencies (16+1)/20 and (2+1)/20.
+	posteriors := mh.PosteriorGenotypes()
+	if len(posteriors) != 3 || strings.Join(posteriors[0].Genotype, "/") != "A-T/A-T" ||
+		math.Abs(posteriors[0].Probability-0.85*0.85) > 1e-9 {
+		t.Errorf("prior %v, want A-T/A-T at %g first", posteriors, 0.85*0.85)
+	}
+
+	// At low depth, one read of the rare allele makes a heterozygote, not a rare homozygote.
+	mh.Fragments = map[AlleleMH][2]float64{"C-G": {1, 0}, "A-.": {0, 1}}
+	posteriors = mh.PosteriorGenotypes()
+	if strings.Join(posteriors[0].Genotype, "/") != "A-T/C-G" || posteriors[0].Probability < 0.9 {
+		t.Errorf("posterior %v, want A-T/C-G most likely", posteriors)
+	}
+	var copies float64
+	for _, n := range Dosage(posteriors) {
+		copies += n
+	}
+	if math.Abs(copies-2) > 1e-9 {
+		t.Errorf("dosage adds up to %g copies, want 2", copies)
+	}
+}
+
+func TestPosteriorGenotypesPloidy(t *testing.T) {
+	population := map[string][]AlleleMH{"sa": {"A-T", "A-T"}, "sb": {"A-T", "C-G"}, "sc": {"A-T", "G-G"}}
+	for _, c := range []struct {
+		ploidy    int
+		alt       string
+		fragments map[AlleleMH][2]float64
+		genotypes int
+		want      string
+	}{
+		// Three copies in four of A-T are told apart from two by the depth of C-G, with G-G only in the population.
+		{4, "C-G", map[AlleleMH][2]float64{"A-T": {15, 15}, "C-G": {5, 5}}, 15, "A-T/A-T/A-T/C-G"},
+		{3, "C-G,G-G", map[AlleleMH][2]float64{"A-T": {10, 10}, "C-G": {5, 5}, "G-G": {5, 5}}, 10, "A-T/C-G/G-G"},
+		{1, "C-G,G-G", map[AlleleMH][2]float64{"C-G": {5, 5}}, 3, "C-G"},
+	} {
+		options := DefaultOptions()
+		options.Ploidy = c.ploidy
+		mh := NewMH(VCFFormat{CHROM: "chr1", POS: 10, ID: "mh", REF: "A-T", ALT: c.alt, options: &options}, []uint64{5})
+		mh.Population, mh.Fragments = population, c.fragments
+
+		posteriors := mh.PosteriorGenotypes()
+		if len(posteriors) != c.genotypes {
+			t.Errorf("ploidy %d: %d genotypes, want %d", c.ploidy, len(posteriors), c.genotypes)
+			continue
+		}
+		if got := strings.Join(posteriors[0].Genotype, "/"); got != c.want {
+			t.Errorf("ploidy %d: most likely %s, want %s", c.ploidy, got, c.want)
+		}
+		var probability, copies float64
+		for _, posterior := range posteriors {
+			probability += posterior.Probability
+			if len(posterior.Genotype) != c.ploidy {
+				t.Errorf("ploidy %d: genotype %v", c.ploidy, posterior.Genotype)
+			}
+		}
+		for _, n := range Dosage(posteriors) {
+			copies += n
+		}
+		if math.Abs(probability-1) > 1e-9 || math.Abs(copies-float64(c.ploidy)) > 1e-9 {
+			t.Errorf("ploidy %d: probabilities add up to %g and dosage to %g copies", c.ploidy, probability, copies)
+		}
+	}
+}
+
+func TestPosteriorGenotypesRealign(t *testing.T) {
+	// A-A-G is common in the population, and A-A-A rare.
+	var population = map[string][]AlleleMH{"sa": {"A-A-A", "A-A-G"}}
+	for i := 0; i < 8; i++ {
+		population["s"+string(rune('b'+i))] = []AlleleMH{"A-A-G", "A-A-G"}
+	}
+	mh := NewMH(VCFFormat{CHROM: "chr1", POS: 25, ID: "mh", REF: "A-A-A", ALT: "A-A-G"}, []uint64{5, 10})
+	mh.Population = population
+	mh.realign = &alleleSequences{alleles: []AlleleMH{"A-A-A", "A-A-G"}}
+	// Reads ending before the SNP at 35 are as likely given both alleles, though Fragments would count them as
+	// A-A-A once partial reads are mistaken for full ones.
+	mh.Fragments = map[AlleleMH][2]float64{"A-A-A": {20, 0}}
+	for i := 0; i < 20; i++ {
+		mh.likelihoods = append(mh.likelihoods, readLikelihood{likelihood: []float64{-1, -1}})
+	}
+	// One read aligned to no allele within the band is no evidence.
+	mh.likelihoods = append(mh.likelihoods, readLikelihood{likelihood: []float64{math.Inf(-1), math.Inf(-1)}})
+
+	posteriors := mh.PosteriorGenotypes()
+	prior := mh.AlleleFrequency()["A-A-G"]
+	if strings.Join(posteriors[0].Genotype, "/") != "A-A-G/A-A-G" ||
+		math.Abs(posteriors[0].Probability-prior*prior) > 1e-9 {
+		t.Errorf("posterior %v, want the prior %g of A-A-G/A-A-G", posteriors, prior*prior)
+	}
+
+	// Reads far more likely given A-A-A make it a homozygote, and a novel read is compared SNP by SNP.
+	for i := range mh.likelihoods[:20] {
+		mh.likelihoods[i].likelihood = []float64{-1, -20}
+	}
+	mh.likelihoods = append(mh.likelihoods, readLikelihood{novel: "C-A-A"})
+	if posteriors = mh.PosteriorGenotypes(); strings.Join(posteriors[0].Genotype, "/") != "A-A-A/A-A-A" {
+		t.Errorf("posterior %v, want A-A-A/A-A-A most likely", posteriors)
+	}
+}
@@ -0,0 +1,212 @@
+package typing
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Primer is a line of primer BED file. Start is 0-based and End is exclusive, as BED format.
+type Primer struct {
+	Name       string
+	CHROM      string
+	Start, End int64
+	Reverse    bool
+}
+
+// PrimerAmplicon is an amplicon defined by its left (forward) and right (reverse) primers.
+type PrimerAmplicon struct {
+	Name        string
+	CHROM       string
+	Left, Right []Primer
+
+	Reads    uint64 // reads whose ends match the primers.
+	Masked   uint64 // bases masked under the primers.
+	Partial  uint64 // reads matching only one primer.
+	Complete uint64 // reads matching both primers.
+}
+
+// Span returns the 1-based first and last positions from the left primer to the right primer.
+func (a *PrimerAmplicon) Span() (start, end int64) {
+	start, end = -1, -1
+	for _, p := range append(append([]Primer{}, a.Left...), a.Right...) {
+		if start == -1 || p.Start+1 < start {
+			start = p.Start + 1
+		}
+		if p.End > end {
+			end = p.End
+		}
+	}
+	return
+}
+
+// PrimerSet holds the amplicons of a primer BED file.
+type PrimerSet struct {
+	Amplicons []*PrimerAmplicon
+	byName    map[string]*PrimerAmplicon
+	OffTarget uint64 // reads matching no primer.
+}
+
+// primerSuffix matches the suffixes distinguishing the primers of an amplicon, e.g. amp1_LEFT, amp1_RIGHT_alt1.
+var primerSuffix = regexp.MustCompile(`(?i)[_\-.](LEFT|RIGHT|FWD|REV|F|R)([_\-.]?alt\d*)?$`)
+
+// NewPrimerSet imports primers from a BED file (CHROM START END NAME [SCORE STRAND]), grouped into amplicons by
+// primer name without its suffix. The strand is taken from the sixth column, or else from the suffix.
+func NewPrimerSet(file io.Reader) (*PrimerSet, error) {
+	var set = &PrimerSet{byName: make(map[string]*PrimerAmplicon)}
+	scanner := NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") || fields[0] == "track" || fields[0] == "browser" {
+			continue
+		}
+		start, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid START: %w", fields[3], err)
+		}
+		end, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid END: %w", fields[3], err)
+		}
+		primer := Primer{Name: fields[3], CHROM: fields[0], Start: start, End: end}
+
+		var suffix string
+		if match := primerSuffix.FindStringSubmatch(primer.Name); match != nil {
+			suffix = strings.ToUpper(match[1])
+		}
+		switch {
+		case len(fields) >= 6 && (fields[5] == "+" || fields[5] == "-"):
+			primer.Reverse = fields[5] == "-"
+		default:
+			primer.Reverse = suffix == "RIGHT" || suffix == "REV" || suffix == "R"
+		}
+
+		name := primerSuffix.ReplaceAllString(primer.Name, "")
+		amplicon, ok := set.byName[name]
+		if !ok {
+			amplicon = &PrimerAmplicon{Name: name, CHROM: primer.CHROM}
+			set.byName[name] = amplicon
+			set.Amplicons = append(set.Amplicons, amplicon)
+		}
+		if primer.Reverse {
+			amplicon.Right = append(amplicon.Right, primer)
+		} else {
+			amplicon.Left = append(amplicon.Left, primer)
+		}
+	}
+	return set, scanner.Err()
+}
+
+// fresh returns a copy of the primer set without counts of reads.
+func (set *PrimerSet) fresh() *PrimerSet {
+	var copied = &PrimerSet{byName: make(map[string]*PrimerAmplicon, len(set.Amplicons))}
+	for _, a := range set.Amplicons {
+		amplicon := &PrimerAmplicon{Name: a.Name, CHROM: a.CHROM, Left: a.Left, Right: a.Right}
+		copied.Amplicons = append(copied.Amplicons, amplicon)
+		copied.byName[amplicon.Name] = amplicon
+	}
+	return copied
+}
+
+// alignedEnd returns the last reference position covered by the alignment.
+func (s *SAM) alignedEnd() int64 {
+	return s.pos + int64(len(s.RefToQuery())) - 1
+}
+
+// Match returns the amplicon whose left primer starts where the read starts, or whose right primer ends where
+// the read ends, within tolerance bases. An amplicon matching both ends is preferred.
+func (set *PrimerSet) Match(sam *SAM, tolerance int64) (best *PrimerAmplicon, both bool) {
+	var (
+		start = sam.pos
+		end   = sam.alignedEnd()
+		abs   = func(x int64) int64 {
+			if x < 0 {
+				return -x
+			}
+			return x
+		}
+	)
+	for _, amplicon := range set.Amplicons {
+		if amplicon.CHROM != sam.chr {
+			continue
+		}
+		var left, right bool
+		for _, p := range amplicon.Left {
+			left = left || abs(start-(p.Start+1)) <= tolerance
+		}
+		for _, p := range amplicon.Right {
+			right = right || abs(end-p.End) <= tolerance
+		}
+		if left && right {
+			return amplicon, true
+		}
+		if (left || right) && best == nil {
+			best = amplicon
+		}
+	}
+	return
+}
+
+// Mask replaces read bases aligned under the given primers by N, and returns the number of masked bases.
+func (sam *SAM) Mask(primers []Primer) (masked uint64) {
+	var (
+		seq        = []byte(sam.seq)
+		refToQuery = sam.RefToQuery()
+	)
+	for _, p := range primers {
+		if p.CHROM != sam.chr {
+			continue
+		}
+		// Primer covers 1-based positions from Start+1 to End.
+		for pos := max(p.Start+1, sam.pos); pos <= p.End && pos-sam.pos < int64(len(refToQuery)); pos++ {
+			if q := refToQuery[pos-sam.pos]; q >= 0 && q < int64(len(seq)) && seq[q] != 'N' {
+				seq[q] = 'N'
+				masked++
+			}
+		}
+	}
+	sam.seq = string(seq)
+	return
+}
+
+// Apply assigns the read to the amplicon whose primers match its ends within tolerance bases, and masks bases under
+// the primers of that amplicon. Off-target reads keep no amplicon, and have bases masked under all primers, or are
+// dropped with dropOffTarget. Reads and masked bases of each amplicon, and off-target reads are counted.
+// It reports whether the read is kept.
+func (set *PrimerSet) Apply(sam *SAM, tolerance int64, dropOffTarget bool) bool {
+	amplicon, both := set.Match(sam, tolerance)
+	if amplicon == nil {
+		set.OffTarget++
+		if dropOffTarget {
+			return false
+		}
+		var all []Primer
+		for _, a := range set.Amplicons {
+			all = append(append(all, a.Left...), a.Right...)
+		}
+		sam.Mask(all)
+		return true
+	}
+	amplicon.Reads++
+	if both {
+		amplicon.Complete++
+	} else {
+		amplicon.Partial++
+	}
+	sam.amplicon = amplicon.Name
+	amplicon.Masked += sam.Mask(append(append([]Primer{}, amplicon.Left...), amplicon.Right...))
+	return true
+}
+
+// Covers reports whether the amplicon assigned to the read spans from start to end (1-based) of chrom.
+// Reads without amplicon cover any region.
+func (set *PrimerSet) Covers(sam *SAM, chrom string, start, end int64) bool {
+	if sam.amplicon == "" {
+		return true
+	}
+	amplicon := set.byName[sam.amplicon]
+	first, last := amplicon.Span()
+	return amplicon.CHROM == chrom && first <= start && end <= last
+}
@@ -0,0 +1,110 @@
+package typing
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewPrimerSet(t *testing.T) {
+	const bed = "track name=primers\n" +
+		"chr1\t0\t20\tamp1_LEFT\n" +
+		"chr1\t100\t120\tamp1_RIGHT\n" +
+		"chr1\t200\t220\tamp2-F\n" +
+		"chr1\t300\t320\tamp2-r\n" +
+		"chr1\t400\t420\tamp3.fwd_alt1\n" +
+		"chr1\t500\t520\tamp3_REV-alt2\n" +
+		"chr1\t600\t620\tamp4_FR\t0\t-\n" // no suffix, strand from the sixth column.
+	set, err := NewPrimerSet(strings.NewReader(bed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		name        string
+		left, right int
+	}{
+		{"amp1", 1, 1},
+		{"amp2", 1, 1},
+		{"amp3", 1, 1},
+		{"amp4_FR", 0, 1},
+	}
+	if len(set.Amplicons) != len(cases) {
+		t.Fatalf("%d amplicons, want %d", len(set.Amplicons), len(cases))
+	}
+	for i, c := range cases {
+		if a := set.Amplicons[i]; a.Name != c.name || len(a.Left) != c.left || len(a.Right) != c.right {
+			t.Errorf("amplicon %d %s with %d left and %d right primers, want %s with %d and %d",
+				i, a.Name, len(a.Left), len(a.Right), c.name, c.left, c.right)
+		}
+	}
+	if start, end := set.Amplicons[0].Span(); start != 1 || end != 120 {
+		t.Errorf("amp1 spans %d-%d, want 1-120", start, end)
+	}
+	if _, err := NewPrimerSet(strings.NewReader("chr1\tx\t20\tamp1_LEFT\n")); err == nil {
+		t.Error("invalid START accepted")
+	}
+}
+
+func TestPrimerSetApply(t *testing.T) {
+	// amp1 covers 1-120 and amp2 201-320 of chr1.
+	primers, err := NewPrimerSet(strings.NewReader("chr1\t0\t20\tamp1_LEFT\nchr1\t100\t120\tamp1_RIGHT\n" +
+		"chr1\t200\t220\tamp2_LEFT\nchr1\t300\t320\tamp2_RIGHT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		name          string
+		pos           int
+		length        int
+		dropOffTarget bool
+		kept          bool
+		amplicon      string
+		masked        int
+	}{
+		{"left primer", 1, 30, false, true, "amp1", 20},
+		{"within tolerance", 5, 30, false, true, "amp1", 16},
+		{"beyond tolerance", 8, 30, false, true, "", 13}, // masked under all primers.
+		{"dropped", 8, 30, true, false, "", 0},
+		{"right primer", 291, 30, false, true, "amp2", 20},
+		{"both primers", 1, 120, false, true, "amp1", 40},
+	}
+	for _, c := range cases {
+		set := primers.fresh()
+		sam, err := NewSAM("r\t0\tchr1\t" + strconv.Itoa(c.pos) + "\t60\t" + strconv.Itoa(c.length) + "M\t*\t0\t0\t" +
+			strings.Repeat("A", c.length) + "\t*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := set.Apply(sam, 5, c.dropOffTarget); kept != c.kept || sam.amplicon != c.amplicon {
+			t.Errorf("%s: kept %v in amplicon %q, want %v in %q", c.name, kept, sam.amplicon, c.kept, c.amplicon)
+		}
+		if masked := strings.Count(sam.seq, "N"); c.kept && masked != c.masked {
+			t.Errorf("%s: %d bases masked, want %d", c.name, masked, c.masked)
+		}
+	}
+
+	set := primers.fresh()
+	inAmp1, _ := NewSAM("r\t0\tchr1\t1\t60\t30M\t*\t0\t0\t" + strings.Repeat("A", 30) + "\t*")
+	offTarget, _ := NewSAM("r\t0\tchr1\t50\t60\t30M\t*\t0\t0\t" + strings.Repeat("A", 30) + "\t*")
+	set.Apply(inAmp1, 5, false)
+	set.Apply(offTarget, 5, false)
+	if a := set.Amplicons[0]; a.Reads != 1 || a.Partial != 1 || a.Masked != 20 || set.OffTarget != 1 {
+		t.Errorf("amp1 counted %+v with %d off-target reads", *a, set.OffTarget)
+	}
+	for _, c := range []struct {
+		sam        *SAM
+		chrom      string
+		start, end int64
+		covered    bool
+	}{
+		{inAmp1, "chr1", 50, 60, true},
+		{inAmp1, "chr1", 110, 130, false}, // beyond the right primer of amp1.
+		{inAmp1, "chr1", 250, 260, false}, // in amp2.
+		{inAmp1, "chr2", 50, 60, false},
+		{offTarget, "chr1", 250, 260, true},
+	} {
+		if got := set.Covers(c.sam, c.chrom, c.start, c.end); got != c.covered {
+			t.Errorf("read at %d covers %s:%d-%d %v, want %v", c.sam.pos, c.chrom, c.start, c.end, got, c.covered)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package typing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Reason codes of a no-call or low-confidence call in QC table.
+const (
+	ReasonNoDepth         = "NO_DEPTH"         // no read covers the marker.
+	ReasonNoCopy          = "NO_COPY"          // the marker has no copy in the sample, e.g. Y-linked in a female.
+	ReasonLowDepth        = "LOW_DEPTH"        // depth is less than Options.MinDepth.
+	ReasonMultiAllele     = "MULTI_ALLELE"     // more alleles than copies of the marker pass Options.MinFreq.
+	ReasonRareDominant    = "RARE_DOMINANT"    // a rare allele is deeper than any known allele.
+	ReasonAlleleImbalance = "ALLELE_IMBALANCE" // minor to major allele ratio of a heterozygote is less than Options.MinBalance.
+	ReasonStrandBias      = "STRAND_BIAS"      // an allele passing Options.MinFreq failed the strand bias test.
+	ReasonStrandImbalance = "STRAND_IMBALANCE" // reads of the marker come almost from one strand.
+	ReasonHighPartial     = "HIGH_PARTIAL"     // most reads miss some SNPs of the microhaplotype.
+	ReasonMateConflict    = "MATE_CONFLICT"    // mates of many read pairs disagree on the microhaplotype.
+)
+
+const (
+	// minStrandBalance is the minimum fraction of reads on the minor strand.
+	minStrandBalance = 0.1
+	// maxPartialFrac is the maximum fraction of partial reads of a microhaplotype.
+	maxPartialFrac = 0.5
+	// maxConflictFrac is the maximum fraction of read pairs whose mates disagree.
+	maxConflictFrac = 0.1
+)
+
+// QCRecord summarises the evidence behind the call of one marker.
+type QCRecord struct {
+	Marker        string
+	Type          string
+	Depth         float64
+	PartialFrac   float64
+	RareDepth     float64
+	AlleleBalance float64 // NaN for homozygote or no-call.
+	StrandBalance float64 // NaN for no read.
+	Ploidy        int
+	Genotype      []string
+	Reasons       []string
+}
+
+// Status is PASS, LOW_CONF for a call with reasons, or NO_CALL.
+func (r QCRecord) Status() string {
+	switch {
+	case len(r.Genotype) == 0:
+		return "NO_CALL"
+	case len(r.Reasons) > 0:
+		return "LOW_CONF"
+	default:
+		return "PASS"
+	}
+}
+
+// GenotypeString joins the called alleles by "/", or "." for no call.
+func (r QCRecord) GenotypeString() string {
+	if len(r.Genotype) == 0 {
+		return "."
+	}
+	return strings.Join(r.Genotype, "/")
+}
+
+func (r QCRecord) String() string {
+	var reasons = strings.Join(r.Reasons, ",")
+	if reasons == "" {
+		reasons = "."
+	}
+	formatNaN := func(v float64) string {
+		if math.IsNaN(v) {
+			return "."
+		}
+		return fmt.Sprintf("%0.4f", v)
+	}
+	return fmt.Sprintf("%s\t%s\t%0.f\t%0.4f\t%0.f\t%s\t%s\t%d\t%s\t%s\t%s",
+		r.Marker, r.Type, r.Depth, r.PartialFrac, r.RareDepth, formatNaN(r.AlleleBalance), formatNaN(r.StrandBalance),
+		r.Ploidy, r.GenotypeString(), r.Status(), reasons)
+}
+
+// NewQCRecord gathers depth, allele and strand balance of a typed marker and explains its call.
+func NewQCRecord(marker GeneticMarker) QCRecord {
+	var (
+		record            = QCRecord{Marker: marker.GetID(), Ploidy: marker.Ploidy(), AlleleBalance: math.NaN(), StrandBalance: math.NaN()}
+		depth             = marker.AlleleDepth()
+		strand            map[string][2]float64
+		maxKnown, maxRare float64
+		conflictFrac      float64
+		stutter           = make(map[string]bool)
+		opt               = &defaultOptions
+	)
+	switch m := marker.(type) {
+	case SNP:
+		record.Type, record.Genotype, strand, opt = "SNP", m.DetermineGenotype(), m.StrandCount(), m.opts()
+	case MH:
+		record.Type, record.Genotype, strand, opt = "MH", m.DetermineGenotype(), m.StrandCount(), m.opts()
+		if m.Reads > 0 {
+			record.PartialFrac = float64(m.PartialReads) / float64(m.Reads)
+			conflictFrac = float64(m.Conflicts) / float64(m.Reads)
+		}
+		for _, n := range m.RareAlleles {
+			record.RareDepth += n
+			maxRare = math.Max(maxRare, n)
+		}
+	case STR:
+		record.Type, record.Genotype, strand, opt = "STR", m.DetermineGenotype(), m.StrandCount(), m.opts()
+		for sequence := range m.calledSequences().stutter {
+			stutter[m.AlleleName(sequence)] = true
+		}
+	case InDel:
+		record.Type, record.Genotype, strand, opt = m.Kind(), m.DetermineGenotype(), m.StrandCount(), m.opts()
+		for _, n := range m.RareAlleles {
+			record.RareDepth += n
+			maxRare = math.Max(maxRare, n)
+		}
+	}
+	for _, n := range depth {
+		record.Depth += n
+		maxKnown = math.Max(maxKnown, n)
+	}
+	record.Depth += record.RareDepth
+
+	var (
+		passed     []string
+		strandBias bool
+		reads      [2]float64
+	)
+	for allele, n := range depth {
+		if record.Depth-record.RareDepth > 0 && n/(record.Depth-record.RareDepth) > opt.MinFreq && !stutter[allele] {
+			passed = append(passed, allele)
+			strandBias = strandBias || opt.strandBiased(strand, allele)
+		}
+	}
+	for _, v := range strand {
+		reads[Forward] += v[Forward]
+		reads[Reverse] += v[Reverse]
+	}
+	if reads[Forward]+reads[Reverse] > 0 {
+		record.StrandBalance = math.Min(reads[Forward], reads[Reverse]) / (reads[Forward] + reads[Reverse])
+	}
+	if g := record.Genotype; len(g) > 0 && g[0] != g[len(g)-1] {
+		// Genotype is sorted, so that a heterozygote has different first and last alleles.
+		var minimum, maximum = math.Inf(1), 0.0
+		for _, allele := range g {
+			minimum, maximum = math.Min(minimum, depth[allele]), math.Max(maximum, depth[allele])
+		}
+		record.AlleleBalance = minimum / maximum
+	}
+
+	switch {
+	case record.Depth == 0:
+		record.Reasons = append(record.Reasons, ReasonNoDepth)
+	case record.Depth < opt.MinDepth:
+		record.Reasons = append(record.Reasons, ReasonLowDepth)
+	}
+	if record.Ploidy == 0 {
+		record.Reasons = append(record.Reasons, ReasonNoCopy)
+	}
+	if record.Ploidy > 0 && len(passed) > record.Ploidy {
+		record.Reasons = append(record.Reasons, ReasonMultiAllele)
+	}
+	if maxRare > maxKnown {
+		record.Reasons = append(record.Reasons, ReasonRareDominant)
+	}
+	if record.AlleleBalance < opt.MinBalance {
+		record.Reasons = append(record.Reasons, ReasonAlleleImbalance)
+	}
+	if strandBias {
+		record.Reasons = append(record.Reasons, ReasonStrandBias)
+	}
+	if record.StrandBalance < minStrandBalance {
+		record.Reasons = append(record.Reasons, ReasonStrandImbalance)
+	}
+	if record.PartialFrac > maxPartialFrac {
+		record.Reasons = append(record.Reasons, ReasonHighPartial)
+	}
+	if conflictFrac > maxConflictFrac {
+		record.Reasons = append(record.Reasons, ReasonMateConflict)
+	}
+	sort.Strings(record.Reasons)
+	return record
+}
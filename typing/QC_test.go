@@ -0,0 +1,62 @@
+package typing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewQCRecord(t *testing.T) {
+	type depth = map[AlleleMH][2]float64 // forward and reverse reads of each allele.
+	var cases = []struct {
+		name                     string
+		info                     string
+		alleles, rare            depth
+		reads, partial, conflict uint64
+		strandBiasP              float64
+		genotype, reasons        string
+	}{
+		{name: "pass", alleles: depth{"C-T": {15, 15}, "G-A": {15, 15}}, genotype: "C-T/G-A", reasons: ""},
+		{name: "no depth", genotype: ".", reasons: "NO_DEPTH"},
+		{name: "no copy", info: "PLOIDY=0", genotype: ".", reasons: "NO_COPY,NO_DEPTH"},
+		{name: "low depth", alleles: depth{"C-T": {2, 2}, "G-A": {2, 2}}, genotype: "C-T/G-A", reasons: "LOW_DEPTH"},
+		{name: "multi allele", alleles: depth{"C-T": {10, 10}, "G-A": {10, 10}, "G-T": {10, 10}}, genotype: ".",
+			reasons: "MULTI_ALLELE"},
+		{name: "imbalance", alleles: depth{"C-T": {40, 40}, "G-A": {10, 10}}, genotype: "C-T/G-A",
+			reasons: "ALLELE_IMBALANCE"},
+		{name: "rare dominant", alleles: depth{"C-T": {5, 5}}, rare: depth{"C-A": {15, 15}}, genotype: "C-T/C-T",
+			reasons: "RARE_DOMINANT"},
+		{name: "strand imbalance", alleles: depth{"C-T": {40, 0}}, genotype: "C-T/C-T", reasons: "STRAND_IMBALANCE"},
+		// Each allele is tested against the others, so that both fail and nothing is called.
+		{name: "strand bias", alleles: depth{"C-T": {20, 20}, "G-A": {38, 2}}, strandBiasP: 0.05, genotype: ".",
+			reasons: "STRAND_BIAS"},
+		{name: "partial and conflict", alleles: depth{"C-T": {15, 15}}, reads: 30, partial: 20, conflict: 6,
+			genotype: "C-T/C-T", reasons: "HIGH_PARTIAL,MATE_CONFLICT"},
+	}
+	for _, c := range cases {
+		options := DefaultOptions()
+		options.StrandBiasP = c.strandBiasP
+		info := "OFFSET=10"
+		if c.info != "" {
+			info += ";" + c.info
+		}
+		markers, err := NewVCFFormat(strings.NewReader("chr1\t100\tmh\tC-T\tG-A\t.\tPASS\t" + info + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mh := markers[0].(MH)
+		mh.options = &options
+		mh.Alleles = make(map[AlleleMH]float64)
+		for allele, n := range c.alleles {
+			mh.Alleles[allele], mh.Strand[allele] = n[Forward]+n[Reverse], n
+		}
+		for allele, n := range c.rare {
+			mh.RareAlleles[allele], mh.Strand[allele] = n[Forward]+n[Reverse], n
+		}
+		mh.Reads, mh.PartialReads, mh.Conflicts = c.reads, c.partial, c.conflict
+
+		record := NewQCRecord(mh)
+		if got := strings.Join(record.Reasons, ","); record.GenotypeString() != c.genotype || got != c.reasons {
+			t.Errorf("%s: genotype %s with reasons %q, want %s with %q", c.name, record.GenotypeString(), got, c.genotype, c.reasons)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package typing
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// realignFlank is the number of reference bases on each side of a microhaplotype in its allele sequences.
+	realignFlank = 20
+	// realignBand is the maximum shift (bp) between a read and an allele sequence from the read's own alignment.
+	realignBand = 8
+	// realignGapOpen and realignGapExtend are the probabilities of opening and extending an indel in the pair-HMM.
+	realignGapOpen, realignGapExtend = 1e-3, 0.1
+	// realignBaseError is the error probability of bases without quality.
+	realignBaseError = 0.01
+	// realignNovelRatio is how many times more likely a read is given its own bases at the SNPs than given any known
+	// allele, for the read to be left out of the known alleles as a rare allele.
+	realignNovelRatio = 100
+)
+
+// alleleSequences are the known full alleles of a microhaplotype with their sequences in reference context,
+// starting from the reference position start.
+type alleleSequences struct {
+	alleles   []AlleleMH
+	sequences []string
+	start     int64
+	reference string
+}
+
+// sequence returns the reference sequence with the bases of a full allele at the SNPs of the microhaplotype.
+func (set *alleleSequences) sequence(mh MH, allele AlleleMH) string {
+	sequence := []byte(set.reference)
+	for i := 0; i < len(allele); i += 2 {
+		pos := mh.POS - set.start
+		if i > 0 {
+			pos += int64(mh.OffSet[i/2-1])
+		}
+		sequence[pos] = allele[i]
+	}
+	return string(sequence)
+}
+
+// newAlleleSequences builds the sequence of each known full allele by replacing the SNPs of the reference around
+// the microhaplotype. Nil is returned without the reference of the marker.
+func newAlleleSequences(mh MH, reference *FASTA) *alleleSequences {
+	if reference == nil || !reference.Has(mh.CHROM) {
+		return nil
+	}
+	var (
+		start = max(1, mh.POS-realignFlank)
+		end   = mh.POS + int64(mh.OffSet[len(mh.OffSet)-1]) + realignFlank
+		ref   = strings.ToUpper(reference.Fetch(mh.CHROM, start, end))
+		set   = &alleleSequences{start: start, reference: ref}
+	)
+	if int64(len(ref)) <= end-realignFlank-start {
+		return nil // the microhaplotype is beyond the end of the reference sequence.
+	}
+	for allele := range mh.Alleles {
+		if len(allele) != 2*len(mh.OffSet)+1 || strings.Contains(allele, ".") {
+			continue // e.g. "." as REF.
+		}
+		set.alleles = append(set.alleles, allele)
+	}
+	sort.Strings(set.alleles)
+	for _, allele := range set.alleles {
+		set.sequences = append(set.sequences, set.sequence(mh, allele))
+	}
+	if len(set.alleles) == 0 {
+		return nil
+	}
+	return set
+}
+
+// realignMH returns the log-likelihood of the read given each allele of set, by aligning the part of the read
+// within the allele sequences to each of them, and the first and last reference positions of that part.
+// Nil is returned for a read covering no SNP, without SEQ, or rejected by Mutation. A read whose own bases at all
+// SNPs make an unknown allele explaining it realignNovelRatio times better than any known allele is returned as
+// novel.
+func (s *SAM) realignMH(mh MH, set *alleleSequences) (likelihood []float64, span [2]int64, novel AlleleMH) {
+	var (
+		opt        = mh.opts()
+		end        = set.start + int64(len(set.reference)) - 1
+		last       = mh.POS + int64(mh.OffSet[len(mh.OffSet)-1])
+		refToQuery = s.RefToQuery()
+	)
+	if s.chr != mh.CHROM || mh.POS > s.alignedEnd() || last < s.pos {
+		return nil, span, ""
+	}
+	if opt.Primers != nil && !opt.Primers.Covers(s, mh.CHROM, mh.POS, last) {
+		return nil, span, "" // the read comes from another amplicon.
+	}
+	if mh.Mutation(s) {
+		return nil, span, ""
+	}
+
+	// The read is cut at its first and last bases aligned inside the allele sequences.
+	var first, final int64 = -1, -1
+	for pos := max(set.start, s.pos); pos <= min(end, s.pos+int64(len(refToQuery))-1); pos++ {
+		if q := refToQuery[pos-s.pos]; q >= 0 {
+			if first < 0 {
+				first = pos
+			}
+			final = pos
+		}
+	}
+	if first < 0 || first > last || final < mh.POS {
+		return nil, span, ""
+	}
+	var qFirst, qFinal = refToQuery[first-s.pos], refToQuery[final-s.pos]
+	if qFinal >= int64(len(s.seq)) {
+		return nil, span, "" // SEQ "*", e.g. in secondary alignments.
+	}
+	var (
+		read    = strings.ToUpper(s.seq[qFirst : qFinal+1])
+		quality string
+		best    = math.Inf(-1)
+	)
+	if s.qual != "*" && int64(len(s.qual)) > qFinal {
+		quality = s.qual[qFirst : qFinal+1]
+	}
+	for i := range set.sequences {
+		likelihood = append(likelihood, pairHMM(read, quality, set.sequences[i], int(first-set.start)))
+		best = math.Max(best, likelihood[i])
+	}
+
+	// The bases of the read at the SNPs through its own alignment, compared with the known alleles.
+	var bases = make([]string, 0, len(mh.OffSet)+1)
+	for i := -1; i < len(mh.OffSet); i++ {
+		pos := mh.POS
+		if i >= 0 {
+			pos += int64(mh.OffSet[i])
+		}
+		base := "."
+		if j := pos - s.pos; j >= 0 && j < int64(len(refToQuery)) && refToQuery[j] >= 0 &&
+			refToQuery[j] < int64(len(s.seq)) && strings.IndexByte("ACGTacgt", s.seq[refToQuery[j]]) >= 0 {
+			base = strings.ToUpper(string(s.seq[refToQuery[j]]))
+		}
+		bases = append(bases, base)
+	}
+	if observed := strings.Join(bases, "-"); !strings.Contains(observed, ".") && !slices.Contains(set.alleles, observed) &&
+		pairHMM(read, quality, set.sequence(mh, observed), int(first-set.start))-best > math.Log(realignNovelRatio) {
+		novel = observed
+	}
+	return likelihood, [2]int64{first, final}, novel
+}
+
+// readLikelihood is a read, or read pair, with its log-likelihood given each allele of the microhaplotype, or the
+// unknown allele it is left to.
+type readLikelihood struct {
+	likelihood []float64
+	novel      AlleleMH
+	strand     int
+}
+
+// countRealigned adds the likelihoods of a read to the microhaplotype typed by realignment. Both mates of a read
+// pair are one fragment, whose likelihood is the product of both, and which is novel if either mate is.
+func (mh *MH) countRealigned(SAMrecord *SAM) {
+	likelihood, span, novel := SAMrecord.realignMH(*mh, mh.realign)
+	if likelihood == nil {
+		return
+	}
+	strand := readStrand(SAMrecord)
+	if SAMrecord.flag&0x1 == 0 || SAMrecord.flag&0x900 != 0 { // single-end, secondary or supplementary.
+		mh.addLikelihood(likelihood, span, novel, strand)
+		return
+	}
+	if mh.mates == nil {
+		mh.mates = make(map[string]mate)
+	}
+	other, ok := mh.mates[SAMrecord.seqID]
+	if !ok {
+		mh.mates[SAMrecord.seqID] = mate{allele: novel, likelihood: likelihood, span: span, strand: strand,
+			first: SAMrecord.flag&0x40 != 0}
+		return
+	}
+	delete(mh.mates, SAMrecord.seqID)
+	for i := range likelihood {
+		likelihood[i] += other.likelihood[i]
+	}
+	switch {
+	case novel == "":
+		novel = other.allele
+	case other.allele != "":
+		var conflict bool
+		if novel, conflict = joinMates(other.allele, novel); conflict {
+			mh.Conflicts++
+		}
+	}
+	if !other.first { // strand of the fragment is the strand of read 1.
+		other.strand = strand
+	}
+	mh.addLikelihood(likelihood, [2]int64{min(span[0], other.span[0]), max(span[1], other.span[1])}, novel, other.strand)
+}
+
+// addLikelihood records a fragment spanning the reference positions, and assigns it in Fragments to its novel
+// allele, or else to its most likely allele.
+func (mh *MH) addLikelihood(likelihood []float64, span [2]int64, novel AlleleMH, strand int) {
+	mh.Reads++
+	if span[0] > mh.POS || span[1] < mh.POS+int64(mh.OffSet[len(mh.OffSet)-1]) {
+		mh.PartialReads++
+	}
+	mh.likelihoods = append(mh.likelihoods, readLikelihood{likelihood: likelihood, novel: novel, strand: strand})
+
+	var allele = novel
+	if allele == "" {
+		var best int
+		for i := range likelihood {
+			if likelihood[i] > likelihood[best] {
+				best = i
+			}
+		}
+		allele = mh.realign.alleles[best]
+	}
+	if mh.Fragments == nil {
+		mh.Fragments = make(map[AlleleMH][2]float64)
+	}
+	count := mh.Fragments[allele]
+	count[strand]++
+	mh.Fragments[allele] = count
+}
+
+// resolveRealigned sets the depth of known alleles to their expected reads, estimated by EM from the likelihood
+// of each read given each allele, instead of the alleles observed in reads. Novel reads are rare alleles.
+func (mh *MH) resolveRealigned() {
+	var (
+		alleles    = mh.realign.alleles
+		candidates = make([]int, len(alleles))
+		classes    = make([]emClass, 0, len(mh.likelihoods))
+	)
+	for i := range candidates {
+		candidates[i] = i
+	}
+	mh.Strand, mh.AlleleSD = make(map[AlleleMH][2]float64), make(map[AlleleMH]float64)
+	for _, read := range mh.likelihoods {
+		if read.novel != "" {
+			mh.RareAlleles[read.novel]++
+			mh.addStrand(read.novel, read.strand)
+			continue
+		}
+		var (
+			class = emClass{candidates: candidates, likelihood: make([]float64, len(alleles))}
+			top   = math.Inf(-1)
+		)
+		for _, l := range read.likelihood {
+			top = math.Max(top, l)
+		}
+		if math.IsInf(top, -1) {
+			continue // aligned to no allele within the band.
+		}
+		for i, l := range read.likelihood {
+			class.likelihood[i] = math.Exp(l - top)
+		}
+		class.reads[read.strand] = 1
+		classes = append(classes, class)
+	}
+
+	strand, sd := estimate(classes, len(alleles), float64(len(classes)))
+	for i, allele := range alleles {
+		depth := strand[i][Forward] + strand[i][Reverse]
+		mh.Alleles[allele] = depth
+		if depth > 0 {
+			mh.Strand[allele], mh.AlleleSD[allele] = strand[i], sd[i]
+		}
+	}
+}
+
+// pairHMM returns the log-likelihood of the most likely alignment (Viterbi) of the whole read to the sequence,
+// with free ends of the sequence, within realignBand of the diagonal where the read starts.
+func pairHMM(read, quality, sequence string, diagonal int) float64 {
+	var (
+		n, m   = len(read), len(sequence)
+		inf    = math.Inf(-1)
+		open   = math.Log(realignGapOpen)
+		extend = math.Log(realignGapExtend)
+		newRow = func() []float64 {
+			row := make([]float64, m+1)
+			for j := range row {
+				row[j] = inf
+			}
+			return row
+		}
+		// Match, insertion (read base) and deletion (sequence base) states of the current and previous rows.
+		M, I, D    = newRow(), newRow(), newRow()
+		pM, pI, pD = newRow(), newRow(), newRow()
+		lo         = func(i int) int { return max(0, i+diagonal-realignBand) }
+		hi         = func(i int) int { return min(m, i+diagonal+realignBand) }
+	)
+	if lo(0) > m {
+		return inf
+	}
+	// The read may start anywhere in the band.
+	for j := lo(0); j <= hi(0); j++ {
+		M[j] = 0
+	}
+	for i := 1; i <= n; i++ {
+		M, pM = pM, M
+		I, pI = pI, I
+		D, pD = pD, D
+		var (
+			e        = realignBaseError
+			l, h     = lo(i), hi(i)
+			match    float64
+			mismatch float64
+		)
+		if quality != "" {
+			e = math.Min(0.75, math.Pow(10, -float64(int(quality[i-1])-33)/10))
+		}
+		match, mismatch = math.Log(1-e), math.Log(e/3)
+		// Cells next to the band are left from earlier rows, and are cleared.
+		if l > 0 {
+			M[l-1], I[l-1], D[l-1] = inf, inf, inf
+		}
+		if h < m {
+			M[h+1], I[h+1], D[h+1] = inf, inf, inf
+		}
+		for j := l; j <= h; j++ {
+			// Insertion consumes the read.
+			I[j] = math.Max(pM[j]+open, pI[j]+extend)
+			M[j], D[j] = inf, inf
+			if j == 0 {
+				continue
+			}
+			// Deletion consumes the sequence.
+			D[j] = math.Max(M[j-1]+open, D[j-1]+extend)
+			var emission float64
+			switch a, b := read[i-1], sequence[j-1]; {
+			case a == 'N' || b == 'N':
+			case a == b:
+				emission = match
+			default:
+				emission = mismatch
+			}
+			M[j] = emission + math.Max(pM[j-1], math.Max(pI[j-1], pD[j-1]))
+		}
+	}
+	var best = inf
+	for j := lo(n); j <= hi(n); j++ {
+		best = math.Max(best, math.Max(M[j], I[j]))
+	}
+	return best
+}
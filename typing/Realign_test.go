@@ -0,0 +1,129 @@
+package typing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPairHMM(t *testing.T) {
+	var (
+		sequence = "GATTACAGCTTGCAAGGTCC"
+		read     = "TACAGCTTGC"
+	)
+	perfect := pairHMM(read, "", sequence, 3)
+	if want := 10 * math.Log(1-realignBaseError); math.Abs(perfect-want) > 1e-9 {
+		t.Errorf("perfect read log-likelihood %g, want %g", perfect, want)
+	}
+	// The read is found within the band from a shifted diagonal.
+	if got := pairHMM(read, "", sequence, 5); math.Abs(got-perfect) > 1e-9 {
+		t.Errorf("shifted read log-likelihood %g, want %g", got, perfect)
+	}
+	mismatch := pairHMM("TACAGGTTGC", "", sequence, 3)
+	insertion := pairHMM("TACAGACTTGC", "", sequence, 3)
+	if !(mismatch < perfect && insertion < perfect && insertion > 2*math.Log(realignBaseError/3)+perfect) {
+		t.Errorf("log-likelihood of mismatch %g and insertion %g, perfect %g", mismatch, insertion, perfect)
+	}
+}
+
+const realignReference = "GATTACAGCTTGCAAGGTCCATGAACGTTAGCCTAGGCATTCGAGTACCGATGCTAAGCT"
+
+// realignOptions returns options realigning reads to realignReference as chr1, and a microhaplotype with SNPs at
+// 25, 30 and 35, where the reference is A-A-A.
+func realignOptions(t *testing.T) ([]GeneticMarker, Options) {
+	path := filepath.Join(t.TempDir(), "ref.fa")
+	if err := os.WriteFile(path, []byte(">chr1\n"+realignReference+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handle, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { handle.Close() })
+
+	markers, err := NewVCFFormat(strings.NewReader("chr1\t25\tmh\t.\tA-A-A,C-T-G\t.\tPASS\tOFFSET=5,10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := DefaultOptions()
+	options.Realign = true
+	if options.Reference, err = NewFASTA(handle); err != nil {
+		t.Fatal(err)
+	}
+	return markers, options
+}
+
+func TestTypeRealign(t *testing.T) {
+	const reference = realignReference
+	markers, options := realignOptions(t)
+
+	// Reads of C-T-G have an insertion next to the SNP at 30, and are not typed from fixed offsets.
+	var (
+		alt = []byte(reference)
+		sam strings.Builder
+	)
+	alt[24], alt[29], alt[34] = 'C', 'T', 'G'
+	for i := 0; i < 5; i++ {
+		ref := reference[5:55]
+		sam.WriteString("r" + string(rune('a'+i)) + "\t0\tchr1\t6\t60\t50M\t*\t0\t0\t" + ref + "\t*\n")
+		ins := string(alt[5:27]) + "T" + string(alt[27:55])
+		sam.WriteString("i" + string(rune('a'+i)) + "\t16\tchr1\t6\t60\t22M1I28M\t*\t0\t0\t" + ins + "\t*\n")
+	}
+	// A secondary alignment without SEQ is not typed.
+	sam.WriteString("s\t256\tchr1\t6\t0\t50M\t*\t0\t0\t*\t*\n")
+	result, err := NewTyper(markers, options).Type(strings.NewReader(sam.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mh := result.Markers[0].(MH)
+	if got := mh.String(); got != "mh\tA-A-A\tC-T-G" {
+		t.Errorf("typed %q, want A-A-A/C-T-G", got)
+	}
+	if math.Abs(mh.Alleles["C-T-G"]-5) > 0.01 || mh.Strand["C-T-G"][Reverse] < 4.99 {
+		t.Errorf("C-T-G expected depth %g on strands %v, want 5 reverse reads", mh.Alleles["C-T-G"], mh.Strand["C-T-G"])
+	}
+
+	// Without realignment, reads with the insertion are rejected.
+	options.Realign = false
+	if result, err = NewTyper(markers, options).Type(strings.NewReader(sam.String())); err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Markers[0].(MH).Alleles["C-T-G"]; got != 0 {
+		t.Errorf("C-T-G typed from fixed offsets with depth %g", got)
+	}
+}
+
+func TestRealignNovel(t *testing.T) {
+	markers, options := realignOptions(t)
+	var (
+		novel = []byte(realignReference)
+		sam   strings.Builder
+	)
+	// Reads of C-A-G are explained by no known allele, and deeper than A-A-A.
+	novel[24], novel[34] = 'C', 'G'
+	for i := 0; i < 8; i++ {
+		sam.WriteString("n" + string(rune('a'+i)) + "\t" + []string{"0", "16"}[i%2] + "\tchr1\t6\t60\t50M\t*\t0\t0\t" +
+			string(novel[5:55]) + "\t*\n")
+	}
+	for i := 0; i < 3; i++ {
+		sam.WriteString("r" + string(rune('a'+i)) + "\t0\tchr1\t6\t60\t50M\t*\t0\t0\t" + realignReference[5:55] + "\t*\n")
+	}
+	// Reads with mismatches at 27 and 32 inside the microhaplotype are rejected, as without realignment.
+	for i := 0; i < 2; i++ {
+		sam.WriteString("m" + string(rune('a'+i)) + "\t0\tchr1\t6\t60\t50M\t*\t0\t0\t" + realignReference[5:55] +
+			"\t*\tMD:Z:21A4A23\n")
+	}
+	result, err := NewTyper(markers, options).Type(strings.NewReader(sam.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mh := result.Markers[0].(MH)
+	if mh.Reads != 11 || mh.RareAlleles["C-A-G"] != 8 || math.Abs(mh.Alleles["A-A-A"]-3) > 0.01 {
+		t.Errorf("%d reads typed as %v and rare %v, want 3 of A-A-A and 8 of C-A-G", mh.Reads, mh.Alleles, mh.RareAlleles)
+	}
+	if reasons := strings.Join(NewQCRecord(mh).Reasons, ","); !strings.Contains(reasons, ReasonRareDominant) {
+		t.Errorf("QC reasons %q, want %s", reasons, ReasonRareDominant)
+	}
+}
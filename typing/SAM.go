@@ -0,0 +1,355 @@
+package typing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type SAM struct {
+	// First 11 mandatory fields
+	seqID       string
+	flag        uint64
+	chr         string
+	pos         int64
+	mapQ        uint64
+	cigar       string
+	refNext     string
+	posNext     uint64
+	templateLen int64
+	seq         string
+	qual        string
+
+	// Optional fields follow the TAG:TYPE:VALUE format.
+	AuxiliaryTag map[string]string
+
+	// amplicon is the name of amplicon whose primers match the read, given by Options.Primers.
+	amplicon string
+	// refToQuery caches RefToQuery, computed once for the markers of a long read.
+	refToQuery []int64
+}
+
+/*
+NewSAM parses a string to SAM struct.
+The first base in a reference sequence has coordinate 1.
+seqID 99	CHR	POS	42	24M	=	16574679	321	sequence	quality	AS:i:0  XN:i:0  XM:i:0  XO:i:0  XG:i:0  NM:i:0  MD:Z:150	YS:i:-20	YT:Z:CP
+*/
+func NewSAM(record string) (*SAM, error) {
+	var (
+		align = new(SAM)
+		err   error
+	)
+	align.AuxiliaryTag = make(map[string]string)
+
+	if record == "" || record[0] == '@' {
+		return nil, nil
+	}
+	fields := strings.Split(record, "\t")
+	if len(fields) < 11 {
+		return nil, fmt.Errorf("%d fields, expected at least 11", len(fields))
+	}
+	align.seqID = fields[0]
+	if align.flag, err = strconv.ParseUint(fields[1], 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid FLAG %q", fields[1])
+	}
+	align.chr = fields[2]
+	if align.pos, err = strconv.ParseInt(fields[3], 10, 64); err != nil || align.pos < 0 {
+		return nil, fmt.Errorf("invalid POS %q", fields[3])
+	}
+	if align.mapQ, err = strconv.ParseUint(fields[4], 10, 8); err != nil {
+		return nil, fmt.Errorf("invalid MAPQ %q", fields[4])
+	}
+	align.cigar = fields[5]
+	align.refNext = fields[6]
+	if fields[7] != "*" { // some aligners write * for an unavailable PNEXT, i.e. 0.
+		if align.posNext, err = strconv.ParseUint(fields[7], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid PNEXT %q", fields[7])
+		}
+	}
+	if align.templateLen, err = strconv.ParseInt(fields[8], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid TLEN %q", fields[8])
+	}
+	align.seq = fields[9]
+	align.qual = fields[10]
+	if align.qual != "*" && len(align.qual) != len(align.seq) {
+		return nil, fmt.Errorf("%d bases of QUAL, %d of SEQ", len(align.qual), len(align.seq))
+	}
+
+	// Fields not following TAG:TYPE:VALUE are not used in typing, and ignored.
+	for i := 11; i < len(fields); i++ {
+		if len(fields[i]) >= 5 && fields[i][2] == ':' && fields[i][4] == ':' {
+			align.AuxiliaryTag[fields[i][:2]] = fields[i][5:]
+		}
+	}
+
+	return align, nil
+}
+
+// TypingMH returns the allele. If the seq overlaps the microhaplotype, the missing SNPs represent to ".".
+// If the seq doesn't overlap the microhaplotype, empty string was returned.
+func (s *SAM) TypingMH(mh MH) AlleleMH {
+	var (
+		opt  = mh.opts()
+		last = int64(mh.OffSet[len(mh.OffSet)-1]) + mh.POS
+		end  = s.pos + int64(len(s.seq)) - 1
+	)
+	if opt.LongReads {
+		end = s.alignedEnd()
+	}
+
+	// the record don't overlap with MicroHaplotype marker.
+	if s.chr != mh.CHROM ||
+		!opt.LongReads && strings.IndexAny(s.cigar, "IDNSHPX=") != -1 ||
+		mh.POS > end || // ref.first.SNP > align.end
+		last < s.pos { // ref.last.SNP < align.start
+		return ""
+	}
+	if primers := opt.Primers; primers != nil && !primers.Covers(s, mh.CHROM, mh.POS, last) {
+		return "" // the read comes from another amplicon.
+	}
+	if mh.Mutation(s) { // have external mutation in reads. Maybe sequencing errors.
+		return ""
+	}
+
+	// SNPs missing in the overlap or masked by primer are ".".
+	var alleleSNP = make([]string, 0, len(mh.OffSet)+1)
+	for i := -1; i < len(mh.OffSet); i++ {
+		pos := mh.POS
+		if i >= 0 {
+			pos += int64(mh.OffSet[i])
+		}
+		if base := s.readBase(pos, opt); base != 0 {
+			alleleSNP = append(alleleSNP, string(base))
+		} else {
+			alleleSNP = append(alleleSNP, ".")
+		}
+	}
+	return AlleleMH(strings.Join(alleleSNP, "-"))
+}
+
+// TypingSNP returns the allele.
+// VCF format starts from one not zero.
+func (s *SAM) TypingSNP(marker SNP) string {
+	opt := marker.opts()
+	if s.chr != marker.CHROM || !opt.LongReads && (marker.POS-s.pos > int64(len(s.seq)) || marker.POS-1 < s.pos) {
+		return "N"
+	}
+	if opt.LongReads && (marker.POS < s.pos || marker.POS > s.alignedEnd()) {
+		return "N"
+	}
+	if primers := opt.Primers; primers != nil && !primers.Covers(s, marker.CHROM, marker.POS, marker.POS) {
+		return "N" // the read comes from another amplicon.
+	}
+	if opt.LongReads {
+		if base := s.readBase(marker.POS, opt); base != 0 {
+			return string(base)
+		}
+		return "N"
+	}
+	// calculate offset
+	pos := AdjustPos(marker.POS-s.pos, s.cigar)
+
+	// Discriminant
+	// cigar sum is more than the length of sequences, return "N"
+	if int(pos) > len(s.seq) || pos < 0 {
+		return "N"
+	}
+
+	// 1st base having position 1
+	return string(s.seq[pos])
+}
+
+// readBase returns the read base at a reference position, or 0 if the position is not sequenced or the base is
+// masked. Short reads are typed without indels, so bases are indexed from the mapping position. Long reads are
+// indexed through the CIGAR, and bases within Options.IndelWindow of an indel are not trusted.
+func (s *SAM) readBase(pos int64, opt *Options) byte {
+	i := pos - s.pos
+	if opt.LongReads {
+		refToQuery := s.RefToQuery()
+		if i < 0 || i >= int64(len(refToQuery)) || nearIndel(refToQuery, i, opt.IndelWindow) {
+			return 0
+		}
+		i = refToQuery[i]
+	}
+	if i < 0 || i >= int64(len(s.seq)) || s.seq[i] == 'N' {
+		return 0
+	}
+	return s.seq[i]
+}
+
+// nearIndel reports whether a deletion, or an insertion between two aligned bases, is within window reference
+// bases of index i of RefToQuery.
+func nearIndel(refToQuery []int64, i, window int64) bool {
+	for j := max(0, i-window); j <= i+window && j < int64(len(refToQuery)); j++ {
+		if refToQuery[j] < 0 || j > i-window && j > 0 && refToQuery[j-1] >= 0 && refToQuery[j] != refToQuery[j-1]+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// AdjustPos offset sequence index according to cigar value 's'.
+func AdjustPos(pos int64, s string) int64 {
+	// extract cigar number into a slice.
+	nucleotideGroups := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsLetter(r)
+	})
+	restGroups := len(nucleotideGroups)
+
+	for i := 0; i >= 0; {
+		// extract cigar character one by one.
+		i = strings.IndexFunc(s, func(r rune) bool {
+			return unicode.IsLetter(r)
+		})
+		if i == -1 {
+			return pos
+		}
+
+		currentGroup, _ := strconv.ParseInt(nucleotideGroups[len(nucleotideGroups)-restGroups], 10, 64)
+		// each "D" decreases 1
+		// each "I" pluses 1
+		switch s[i] {
+		//case 'M':
+		case 'I':
+			pos += currentGroup
+		case 'D':
+			pos -= currentGroup
+		}
+		restGroups--
+		s = s[:i] + "-" + s[i+1:]
+	}
+	return pos
+}
+
+// CigarOp is an operation of CIGAR string, e.g. 65M is {65, 'M'}.
+type CigarOp struct {
+	Len int64
+	Op  byte
+}
+
+// ParseCigar splits CIGAR string into operations. "*" or malformed CIGAR returns nil.
+func ParseCigar(cigar string) (ops []CigarOp) {
+	var n int64
+	for i := 0; i < len(cigar); i++ {
+		switch c := cigar[i]; {
+		case c >= '0' && c <= '9':
+			n = n*10 + int64(c-'0')
+		case strings.IndexByte("MIDNSHP=X", c) != -1:
+			ops = append(ops, CigarOp{Len: n, Op: c})
+			n = 0
+		default:
+			return nil
+		}
+	}
+	return
+}
+
+// MismatchArray returns mismatched reference positions of the alignment from the MD tag,
+// or by comparing with the reference (may be nil) when the MD tag is missing, like samtools calmd.
+// Nil is returned when neither is available.
+func (s *SAM) MismatchArray(reference *FASTA) MutationArray {
+	if MD, ok := s.AuxiliaryTag["MD"]; ok && MD != "" {
+		return parseMD(MD)
+	}
+	if reference == nil || !reference.Has(s.chr) {
+		return nil
+	}
+	return s.referenceMismatch(reference)
+}
+
+// referenceMismatch compares aligned bases with the reference. Deleted reference bases are marked as mismatches,
+// skipped regions (N) as matches, and ambiguous read bases (N) are never mismatches.
+func (s *SAM) referenceMismatch(reference *FASTA) MutationArray {
+	var (
+		ops    = ParseCigar(s.cigar)
+		refLen int64
+	)
+	for _, op := range ops {
+		if strings.IndexByte("MDN=X", op.Op) != -1 {
+			refLen += op.Len
+		}
+	}
+	var (
+		ref          = reference.Fetch(s.chr, s.pos, s.pos+refLen-1)
+		array        = make(MutationArray, 0, refLen)
+		query, index int64
+	)
+	for _, op := range ops {
+		switch op.Op {
+		case 'M', '=', 'X':
+			for i := int64(0); i < op.Len; i++ {
+				var mismatch bool
+				if query < int64(len(s.seq)) && index < int64(len(ref)) {
+					base := s.seq[query] &^ 0x20 // upper case
+					mismatch = base != 'N' && base != ref[index]
+				}
+				array = append(array, mismatch)
+				query++
+				index++
+			}
+		case 'D':
+			for i := int64(0); i < op.Len; i++ {
+				array = append(array, true)
+			}
+			index += op.Len
+		case 'N':
+			array = append(array, make([]bool, op.Len)...)
+			index += op.Len
+		case 'I', 'S':
+			query += op.Len
+		}
+	}
+	return array
+}
+
+// RefToQuery maps each reference position covered by the alignment, starting from the leftmost mapping position,
+// to the index of the aligned base in the read sequence, or -1 for deleted or skipped reference bases.
+func (s *SAM) RefToQuery() (index []int64) {
+	if s.refToQuery != nil {
+		return s.refToQuery
+	}
+	defer func() {
+		s.refToQuery = index
+	}()
+	var query int64
+	for _, op := range ParseCigar(s.cigar) {
+		switch op.Op {
+		case 'M', '=', 'X':
+			for i := int64(0); i < op.Len; i++ {
+				index = append(index, query+i)
+			}
+			query += op.Len
+		case 'D', 'N':
+			for i := int64(0); i < op.Len; i++ {
+				index = append(index, -1)
+			}
+		case 'I', 'S':
+			query += op.Len
+		}
+	}
+	return
+}
+
+// integerTags are auxiliary tags of integer type, others are written as string type.
+var integerTags = map[string]bool{"NM": true, "AS": true, "XS": true, "NH": true, "HI": true, "XN": true, "XM": true, "XO": true, "XG": true}
+
+// String formats the record as a SAM line, with auxiliary tags sorted by name.
+func (s *SAM) String() string {
+	var tags []string
+	for tag, value := range s.AuxiliaryTag {
+		if integerTags[tag] {
+			tags = append(tags, tag+":i:"+value)
+		} else {
+			tags = append(tags, tag+":Z:"+value)
+		}
+	}
+	sort.Strings(tags)
+	var fields = []string{
+		s.seqID, strconv.FormatUint(s.flag, 10), s.chr, strconv.FormatInt(s.pos, 10),
+		strconv.FormatUint(s.mapQ, 10), s.cigar, s.refNext, strconv.FormatUint(s.posNext, 10),
+		strconv.FormatInt(s.templateLen, 10), s.seq, s.qual,
+	}
+	return strings.Join(append(fields, tags...), "\t")
+}
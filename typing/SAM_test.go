@@ -0,0 +1,137 @@
+package typing
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTypingLongReads(t *testing.T) {
+	var (
+		long = DefaultOptions()
+		mh   = NewMH(VCFFormat{CHROM: "chr1", POS: 3, ID: "mh"}, []uint64{10, 20})
+		snp  = SNP{VCFFormat: VCFFormat{CHROM: "chr1", POS: 13, ID: "snp"}}
+	)
+	long.LongReads, long.IndelWindow = true, 2
+	mh.options, snp.options = &long, &long
+
+	// Reference positions 1-30, with read bases A at the SNPs (3, 13, 23) and C elsewhere.
+	var cases = []struct {
+		cigar, seq string
+		mh, snp    string
+	}{
+		{"30M", "CCACCCCCCCCCACCCCCCCCCACCCCCCC", "A-A-A", "A"},
+		{"6M2I24M", "CCACCCGGCCCCCCACCCCCCCCCACCCCCCC", "A-A-A", "A"},     // insertion away from the SNPs.
+		{"5S6M1D23M", "GGGGGCCACCCCCCCCACCCCCCCCCACCCCCCC", "A-A-A", "A"}, // soft clip and deletion.
+		{"11M1I19M", "CCACCCCCCCCGCACCCCCCCCCACCCCCCC", "A-.-A", "N"},     // insertion next to the SNP at 13.
+		{"12M1D17M", "CCACCCCCCCCCCCCCCCCCCACCCCCCC", "A-.-A", "N"},       // the SNP at 13 is deleted.
+	}
+	for _, c := range cases {
+		sam := &SAM{chr: "chr1", pos: 1, cigar: c.cigar, seq: c.seq}
+		if got := sam.TypingMH(mh); string(got) != c.mh {
+			t.Errorf("%s typed microhaplotype %q, want %q", c.cigar, got, c.mh)
+		}
+		if got := sam.TypingSNP(snp); got != c.snp {
+			t.Errorf("%s typed SNP %q, want %q", c.cigar, got, c.snp)
+		}
+	}
+
+	// Short reads with indels are not typed at microhaplotypes.
+	mh.options = nil
+	if got := (&SAM{chr: "chr1", pos: 1, cigar: "6M2I24M", seq: "CCACCCGGCCCCCCACCCCCCCCCACCCCCCC"}).TypingMH(mh); got != "" {
+		t.Errorf("short read with an insertion typed as %q", got)
+	}
+}
+
+func TestTypeLongRecord(t *testing.T) {
+	markers, err := NewVCFFormat(strings.NewReader("chr1\t5\ts1\tA\tG\t.\tPASS\t.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		seq     = strings.Repeat("C", 4) + "G" + strings.Repeat("C", 99995)
+		record  = "r0\t0\tchr1\t1\t60\t100000M\t*\t0\t0\t" + seq + "\t" + strings.Repeat("I", len(seq)) + "\n"
+		options = DefaultOptions()
+	)
+	options.LongReads = true
+	// Five reads are above the error rate of long reads.
+	result, err := NewTyper(markers, options).Type(strings.NewReader(strings.Repeat(record, 5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Markers[0].String(); got != "s1\tG\tG" {
+		t.Errorf("typed %q from a 100 kb record, want s1 G G", got)
+	}
+}
+
+func TestTypeLongReadsHeterozygote(t *testing.T) {
+	// 11 SNPs at 10, 12, ..., 30, all C in one allele and G in the other.
+	var offset []string
+	for i := 2; i <= 20; i += 2 {
+		offset = append(offset, strconv.Itoa(i))
+	}
+	var (
+		ref     = strings.TrimSuffix(strings.Repeat("C-", 11), "-")
+		alt     = strings.TrimSuffix(strings.Repeat("G-", 11), "-")
+		options = DefaultOptions()
+		sam     strings.Builder
+	)
+	markers, err := NewVCFFormat(strings.NewReader("chr1\t10\tmh\t" + ref + "\t" + alt + "\t.\tPASS\tOFFSET=" +
+		strings.Join(offset, ",") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		seq := []byte(strings.Repeat("A", 40))
+		for pos := 10; pos <= 30; pos += 2 {
+			seq[pos-1] = "CG"[i%2]
+		}
+		sam.WriteString("r" + strconv.Itoa(i) + "\t" + []string{"0", "16"}[i/2%2] + "\tchr1\t1\t60\t40M\t*\t0\t0\t" +
+			string(seq) + "\t*\n")
+	}
+	options.LongReads = true
+	result, err := NewTyper(markers, options).Type(strings.NewReader(sam.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := result.Markers[0].String(), "mh\t"+ref+"\t"+alt; got != want {
+		t.Errorf("typed %q from 50 reads of each allele, want %q", got, want)
+	}
+}
+
+func TestMutationLongRead(t *testing.T) {
+	// A long read from 1 with a soft clip and an insertion before the microhaplotype at 2000 and 2010, and a
+	// mismatch at 2005 far from the start of the read.
+	var (
+		seq  = strings.Repeat("A", 3100)
+		qual = []byte(strings.Repeat("I", 3100))
+		mh   = NewMH(VCFFormat{CHROM: "chr1", POS: 2000, ID: "mh", REF: "A-A", ALT: "C-C"}, []uint64{10})
+	)
+	qual[100+1000+5+2004-1000] = '#' // the base aligned to 2005, after the clip and the insertion.
+	sam, err := NewSAM("r\t0\tchr1\t1\t60\t100S1000M5I1995M\t*\t0\t0\t" + seq + "\t" + string(qual) + "\tMD:Z:2004T995")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		minBaseQual int
+		rejected    bool
+	}{
+		{0, true},
+		{20, false}, // the mismatch is at a low quality base.
+	} {
+		options := DefaultOptions()
+		options.LongReads, options.MinBaseQual = true, c.minBaseQual
+		mh.options = &options
+		if got := mh.Mutation(sam); got != c.rejected {
+			t.Errorf("minimum base quality %d: Mutation() = %v, want %v", c.minBaseQual, got, c.rejected)
+		}
+	}
+
+	// Deleted reference bases in the body are not substitutions.
+	if sam, err = NewSAM("r\t0\tchr1\t1\t60\t2004M2D996M\t*\t0\t0\t" + seq[:3000] + "\t*\tMD:Z:2004^TT996"); err != nil {
+		t.Fatal(err)
+	}
+	if mh.Mutation(sam) {
+		t.Error("a long read rejected for a deletion")
+	}
+}
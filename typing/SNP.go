@@ -0,0 +1,96 @@
+package typing
+
+import "fmt"
+
+type SNP struct {
+	VCFFormat
+
+	// The maximum number of each markers is four alleles.
+	// The four elements of array represents the coverage of each alleles corresponding to ["A", "T", "C", "G"].
+	Alleles [4]uint64
+
+	// Strand holds forward and reverse counts of each allele, in the same order as Alleles.
+	Strand [4][2]uint64
+}
+
+// BASE is a kind of nucleotide in DNA (A, T, G, C).
+type BASE = string
+
+var SortedBASE = [4]BASE{"A", "T", "C", "G"}
+
+// Type assertion at compile time, to check SNP implements GeneticMarker interface.
+var _ GeneticMarker = (*SNP)(nil)
+
+func (snp SNP) GetPOS() int64 {
+	return snp.POS
+}
+func (snp SNP) GetCHROM() string {
+	return snp.CHROM
+}
+func (snp SNP) GetID() string {
+	return snp.ID
+}
+func (snp SNP) AlleleDepth() map[string]float64 {
+	var depth = make(map[string]float64, len(SortedBASE))
+	for i, base := range SortedBASE {
+		depth[base] = float64(snp.Alleles[i])
+	}
+	return depth
+}
+func (snp SNP) GetAlleles() []uint64 {
+	return snp.Alleles[:]
+}
+
+// StrandCount returns forward and reverse counts of each allele keyed by base.
+func (snp SNP) StrandCount() map[string][2]float64 {
+	var strand = make(map[string][2]float64, len(SortedBASE))
+	for i, base := range SortedBASE {
+		strand[base] = [2]float64{float64(snp.Strand[i][Forward]), float64(snp.Strand[i][Reverse])}
+	}
+	return strand
+}
+
+func (snp SNP) VerboseString() string {
+	return fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%s",
+		snp.ID, snp.Alleles[0], snp.Alleles[1], snp.Alleles[2], snp.Alleles[3], strandToString(snp.StrandCount()))
+}
+
+func (snp SNP) String() string {
+	return genotypeString(snp.ID, snp.DetermineGenotype(), snp.Ploidy())
+}
+
+// DetermineGenotype removes less than three percent of BASE from four possibility, and BASE explained by
+// sequencing errors with -error_call.
+func (snp SNP) DetermineGenotype() []BASE {
+	var count = snp.Alleles[0] + snp.Alleles[1] + snp.Alleles[2] + snp.Alleles[3]
+	var genotype []BASE
+	var strand = snp.StrandCount()
+	var opt = snp.opts()
+	for i, base := range SortedBASE {
+		if float64(snp.Alleles[i])/float64(count) > opt.MinFreq && !opt.strandBiased(strand, base) &&
+			opt.errorModel.AboveNoise(float64(snp.Alleles[i]), float64(count)) {
+			genotype = append(genotype, base)
+		}
+	}
+
+	// According to the number of retained alleles and the ploidy, the genotype or failure would be determined.
+	return callGenotype(genotype, snp.AlleleDepth(), snp.Ploidy())
+}
+
+// count adds the allele of a read to the SNP.
+func (snp *SNP) count(SAMrecord *SAM) {
+	allele := SAMrecord.TypingSNP(*snp)
+	if allele == "N" {
+		return
+	}
+	for i, base := range SortedBASE {
+		if allele == base {
+			snp.Alleles[i]++
+			snp.Strand[i][readStrand(SAMrecord)]++
+		}
+	}
+}
+
+func (snp *SNP) typed() GeneticMarker {
+	return *snp
+}
@@ -0,0 +1,230 @@
+package typing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// STR is a short tandem repeat marker, declared in VCF by INFO keys MOTIF (the repeat unit, e.g. AGAT) and END
+// (the last position of the repeat region starting at POS). Alleles are keyed by their sequence, and named by
+// repeat count and bracketed sequence, e.g. 11_[AGAT]9AGGT[AGAT]1.
+type STR struct {
+	VCFFormat
+	Motif string
+	End   int64
+
+	Alleles map[string]float64 // depth of each sequence observed in the repeat region.
+	Strand  map[string][2]float64
+}
+
+// Type assertion at compile time, to check STR implements GeneticMarker interface.
+var _ GeneticMarker = (*STR)(nil)
+
+// NewSTR builds an STR from a VCF record with MOTIF and END.
+func NewSTR(record VCFFormat) (STR, error) {
+	var str = STR{VCFFormat: record, End: record.POS, Alleles: make(map[string]float64),
+		Strand: make(map[string][2]float64)}
+	if v, ok := record.INFO["MOTIF"]; ok && len(v) > 0 {
+		str.Motif = strings.ToUpper(fmt.Sprint(v[0]))
+	}
+	if v, ok := record.INFO["END"]; ok && len(v) > 0 {
+		end, err := strconv.ParseInt(fmt.Sprint(v[0]), 10, 64)
+		if err != nil {
+			return str, fmt.Errorf("%s: invalid END: %w", record.ID, err)
+		}
+		str.End = end
+	} else if record.REF != "." {
+		str.End = record.POS + int64(len(record.REF)) - 1
+	}
+	return str, nil
+}
+
+func (str STR) GetPOS() int64 {
+	return str.POS
+}
+func (str STR) GetCHROM() string {
+	return str.CHROM
+}
+func (str STR) GetID() string {
+	return str.ID
+}
+
+// RepeatCount returns the number of repeat units of a sequence, with the remaining bases after a dot, e.g. 9.3.
+func (str STR) RepeatCount(sequence string) string {
+	if str.Motif == "" {
+		return strconv.Itoa(len(sequence))
+	}
+	n, remainder := len(sequence)/len(str.Motif), len(sequence)%len(str.Motif)
+	if remainder == 0 {
+		return strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%d.%d", n, remainder)
+}
+
+// Bracket writes runs of the motif in a sequence as [MOTIF]n, keeping other bases, e.g. [AGAT]9AGGT[AGAT]1.
+func (str STR) Bracket(sequence string) string {
+	if str.Motif == "" {
+		return sequence
+	}
+	var (
+		s strings.Builder
+		m = len(str.Motif)
+	)
+	for i := 0; i < len(sequence); {
+		n := 0
+		for i+(n+1)*m <= len(sequence) && sequence[i+n*m:i+(n+1)*m] == str.Motif {
+			n++
+		}
+		if n > 0 {
+			s.WriteString(fmt.Sprintf("[%s]%d", str.Motif, n))
+			i += n * m
+		} else {
+			s.WriteByte(sequence[i])
+			i++
+		}
+	}
+	return s.String()
+}
+
+// AlleleName names a sequence by its repeat count and bracketed sequence.
+func (str STR) AlleleName(sequence string) string {
+	return str.RepeatCount(sequence) + "_" + str.Bracket(sequence)
+}
+
+// AlleleDepth returns the depth of each sequence keyed by its allele name.
+func (str STR) AlleleDepth() map[string]float64 {
+	var depth = make(map[string]float64, len(str.Alleles))
+	for sequence, n := range str.Alleles {
+		depth[str.AlleleName(sequence)] = n
+	}
+	return depth
+}
+
+// StrandCount returns forward and reverse counts keyed by allele name.
+func (str STR) StrandCount() map[string][2]float64 {
+	var strand = make(map[string][2]float64, len(str.Strand))
+	for sequence, count := range str.Strand {
+		strand[str.AlleleName(sequence)] = count
+	}
+	return strand
+}
+
+func (str STR) String() string {
+	return genotypeString(str.ID, str.DetermineGenotype(), str.Ploidy())
+}
+
+// VerboseString prints the depth of each allele, in the same columns as microhaplotypes. Stutter products
+// excluded from the genotype are listed in the rare allele column.
+func (str STR) VerboseString() string {
+	var (
+		depth   float64
+		alleles = make(map[string]float64)
+		stutter = make(map[string]float64)
+		called  = str.calledSequences()
+	)
+	for sequence, n := range str.Alleles {
+		depth += n
+		if _, ok := called.stutter[sequence]; ok {
+			stutter[str.AlleleName(sequence)] = n
+		} else {
+			alleles[str.AlleleName(sequence)] = n
+		}
+	}
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s", str.CHROM, str.POS, str.ID,
+		mapToString(alleles, depth, str.opts()), mapToString(stutter, depth, str.opts()), strandToString(str.StrandCount()))
+}
+
+// isStutter reports whether child is parent with one repeat unit less (n-1) or more (n+1).
+func isStutter(child, parent, motif string) bool {
+	var m = len(motif)
+	switch {
+	case m == 0:
+		return false
+	case len(child) == len(parent)-m:
+		child, parent = parent, child
+	case len(child) != len(parent)+m:
+		return false
+	}
+	// Now child is the longer one, with one motif inserted into parent.
+	for i := 0; i+m <= len(child); i++ {
+		if child[i:i+m] == motif && child[:i]+child[i+m:] == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// strCall is the sequences kept in the genotype, and the stutter products with their parent sequence.
+type strCall struct {
+	alleles []string
+	stutter map[string]string
+}
+
+// calledSequences keeps sequences more frequent than Options.MinFreq, without strand bias and not explained by
+// sequencing errors, then drops the stutter products at n-1/n+1 of deeper alleles whose depth is at most
+// Options.Stutter of their parent.
+func (str STR) calledSequences() (call strCall) {
+	var (
+		count     float64
+		sequences []string
+		opt       = str.opts()
+	)
+	call.stutter = make(map[string]string)
+	for _, n := range str.Alleles {
+		count += n
+	}
+	for sequence, n := range str.Alleles {
+		if n/count > opt.MinFreq && !opt.strandBiased(str.Strand, sequence) && opt.errorModel.AboveNoise(n, count) {
+			sequences = append(sequences, sequence)
+		}
+	}
+	// Deeper alleles first, so that stutter is attributed to the deepest parent.
+	sort.Slice(sequences, func(i, j int) bool {
+		if str.Alleles[sequences[i]] == str.Alleles[sequences[j]] {
+			return sequences[i] < sequences[j]
+		}
+		return str.Alleles[sequences[i]] > str.Alleles[sequences[j]]
+	})
+	for _, sequence := range sequences {
+		var parent string
+		for _, kept := range call.alleles {
+			if isStutter(sequence, kept, str.Motif) && str.Alleles[sequence] <= opt.Stutter*str.Alleles[kept] {
+				parent = kept
+				break
+			}
+		}
+		if parent != "" {
+			call.stutter[sequence] = parent
+		} else {
+			call.alleles = append(call.alleles, sequence)
+		}
+	}
+	return
+}
+
+// DetermineGenotype returns names of the called alleles after stutter filtering.
+func (str STR) DetermineGenotype() []string {
+	var names []string
+	for _, sequence := range str.calledSequences().alleles {
+		names = append(names, str.AlleleName(sequence))
+	}
+	return callGenotype(names, str.AlleleDepth(), str.Ploidy())
+}
+
+// count adds the sequence of the repeat region in a read spanning it.
+func (str *STR) count(SAMrecord *SAM) {
+	sequence := SAMrecord.spanSequence(str.CHROM, str.POS, str.End, str.opts())
+	if sequence == "" {
+		return
+	}
+	str.Alleles[sequence]++
+	count := str.Strand[sequence]
+	count[readStrand(SAMrecord)]++
+	str.Strand[sequence] = count
+}
+
+func (str *STR) typed() GeneticMarker {
+	return *str
+}
@@ -0,0 +1,33 @@
+package typing
+
+import "testing"
+
+func TestSTRAllele(t *testing.T) {
+	var str = STR{Motif: "AGAT"}
+	var names = map[string]string{
+		"AGATAGATAGAT":     "3_[AGAT]3",
+		"AGATAGGTAGATAG":   "3.2_[AGAT]1AGGT[AGAT]1AG",
+		"TAGATAGATAGATAGA": "4_T[AGAT]3AGA",
+	}
+	for sequence, want := range names {
+		if got := str.AlleleName(sequence); got != want {
+			t.Errorf("%s named %s, want %s", sequence, got, want)
+		}
+	}
+
+	var stutter = []struct {
+		child, parent string
+		want          bool
+	}{
+		{"AGATAGAT", "AGATAGATAGAT", true},         // n-1
+		{"AGATAGATAGATAGAT", "AGATAGATAGAT", true}, // n+1
+		{"AGATAGGT", "AGATAGATAGGT", true},
+		{"AGGTAGGT", "AGATAGATAGAT", false},
+		{"AGATAGAT", "AGATAGATAGATAGAT", false}, // n-2
+	}
+	for _, c := range stutter {
+		if got := isStutter(c.child, c.parent, "AGAT"); got != c.want {
+			t.Errorf("isStutter(%s, %s) = %v, want %v", c.child, c.parent, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package typing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Strand indexes the forward and reverse counts of an allele.
+const (
+	Forward = 0
+	Reverse = 1
+)
+
+// readStrand returns the strand of a read from the reverse complemented bit (0x10) of FLAG.
+func readStrand(sam *SAM) int {
+	if sam.flag&0x10 != 0 {
+		return Reverse
+	}
+	return Forward
+}
+
+// logFactorial returns log(n!).
+func logFactorial(n int) float64 {
+	v, _ := math.Lgamma(float64(n) + 1)
+	return v
+}
+
+// FisherExact returns the two-sided p-value of Fisher's exact test on the 2x2 table [[a, b], [c, d]].
+func FisherExact(a, b, c, d int) float64 {
+	var (
+		row1, row2 = a + b, c + d
+		col1       = a + c
+		n          = row1 + row2
+	)
+	if n == 0 {
+		return 1
+	}
+	logP := func(x int) float64 {
+		return logFactorial(row1) + logFactorial(row2) + logFactorial(col1) + logFactorial(n-col1) -
+			logFactorial(n) - logFactorial(x) - logFactorial(row1-x) - logFactorial(col1-x) - logFactorial(row2-col1+x)
+	}
+	var (
+		observed = logP(a)
+		p        float64
+		low      = max(0, col1-row2)
+		high     = min(row1, col1)
+	)
+	for x := low; x <= high; x++ {
+		// A relative tolerance is used to include tables as extreme as the observed one.
+		if v := logP(x); v <= observed+1e-7 {
+			p += math.Exp(v)
+		}
+	}
+	return math.Min(p, 1)
+}
+
+// StrandBias tests whether the strand distribution of an allele differs from the other alleles of the marker.
+func StrandBias(strand map[string][2]float64, allele string) float64 {
+	var others [2]float64
+	for k, v := range strand {
+		if k != allele {
+			others[Forward] += v[Forward]
+			others[Reverse] += v[Reverse]
+		}
+	}
+	s := strand[allele]
+	return FisherExact(int(math.Round(s[Forward])), int(math.Round(s[Reverse])),
+		int(math.Round(others[Forward])), int(math.Round(others[Reverse])))
+}
+
+// strandBiased reports whether an allele fails the strand bias test given by StrandBiasP.
+func (o *Options) strandBiased(strand map[string][2]float64, allele string) bool {
+	return o.StrandBiasP > 0 && StrandBias(strand, allele) < o.StrandBiasP
+}
+
+// strandToString prints forward/reverse counts and strand bias p-value of each allele, sorted by total count.
+func strandToString(strand map[string][2]float64) string {
+	var alleles []string
+	for allele, v := range strand {
+		if v[Forward]+v[Reverse] > 0 {
+			alleles = append(alleles, allele)
+		}
+	}
+	sort.Slice(alleles, func(i, j int) bool {
+		a, b := strand[alleles[i]], strand[alleles[j]]
+		if a[Forward]+a[Reverse] == b[Forward]+b[Reverse] {
+			return alleles[i] < alleles[j]
+		}
+		return a[Forward]+a[Reverse] > b[Forward]+b[Reverse]
+	})
+	var s strings.Builder
+	for _, allele := range alleles {
+		v := strand[allele]
+		s.WriteString(fmt.Sprintf("%s:%0.f/%0.f:%0.3g ", allele, v[Forward], v[Reverse], StrandBias(strand, allele)))
+	}
+	return s.String()
+}
@@ -0,0 +1,22 @@
+package typing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFisherExact(t *testing.T) {
+	var cases = []struct {
+		a, b, c, d int
+		want       float64
+	}{
+		{10, 0, 0, 10, 1.0825e-05},
+		{3, 1, 1, 3, 0.4857},
+		{5, 5, 5, 5, 1},
+	}
+	for _, c := range cases {
+		if got := FisherExact(c.a, c.b, c.c, c.d); math.Abs(got-c.want) > 1e-4*math.Max(c.want, 1e-4) {
+			t.Errorf("[[%d %d] [%d %d]] set to %g, want %g", c.a, c.b, c.c, c.d, got, c.want)
+		}
+	}
+}
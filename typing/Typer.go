@@ -0,0 +1,137 @@
+// Package typing types the genetic markers of a panel (SNPs, microhaplotypes, indels and STRs) from the
+// alignments of amplicon sequencing reads, and calls their genotypes.
+package typing
+
+import (
+	"fmt"
+	"io"
+)
+
+// alleleCounter is a marker counting its alleles read by read.
+type alleleCounter interface {
+	count(sam *SAM)
+	// typed returns the marker after the last read.
+	typed() GeneticMarker
+}
+
+// newCounter returns an empty copy of a panel marker typed with the options.
+func newCounter(marker GeneticMarker, opt *Options) alleleCounter {
+	switch m := marker.(type) {
+	case SNP:
+		snp := SNP{VCFFormat: m.VCFFormat}
+		snp.options = opt
+		return &snp
+	case MH:
+		mh := NewMH(m.VCFFormat, m.OffSet)
+		mh.options, mh.Population = opt, m.Population
+		if opt.Realign {
+			mh.realign = newAlleleSequences(mh, opt.Reference)
+		}
+		return &mh
+	case InDel:
+		indel := NewInDel(m.VCFFormat)
+		indel.options = opt
+		return &indel
+	case STR:
+		str := STR{VCFFormat: m.VCFFormat, Motif: m.Motif, End: m.End, Alleles: make(map[string]float64),
+			Strand: make(map[string][2]float64)}
+		str.options = opt
+		return &str
+	}
+	return nil
+}
+
+// Typer types the markers of a panel in the alignments of samples.
+type Typer struct {
+	Markers []GeneticMarker
+	Options Options
+}
+
+// NewTyper returns a Typer of the panel markers, e.g. imported by NewVCFFormat, with the options.
+func NewTyper(markers []GeneticMarker, options Options) *Typer {
+	return &Typer{Markers: markers, Options: options}
+}
+
+// Result holds the typed markers of a sample in panel order, with the sequencing error model estimated from its
+// reads, the reads of each amplicon if primers are given, and the malformed records skipped with Options.Lenient.
+type Result struct {
+	Markers    []GeneticMarker
+	ErrorModel *ErrorModel
+	Primers    *PrimerSet
+	Rejected   []*ParseError
+}
+
+// Type reads the SAM alignments of a sample once, counting alleles of all markers and mismatches of the error model.
+// With primers, reads are assigned to amplicons and masked under primers first. The markers of the Typer are kept
+// unchanged, so that a Typer types any number of samples. A malformed record is returned as a *ParseError.
+func (t *Typer) Type(alignments io.Reader) (*Result, error) {
+	var (
+		opt      = t.Options
+		counters = make([]alleleCounter, len(t.Markers))
+		byChrom  = make(map[string][]alleleCounter)
+		sites    = newErrorSites(t.Markers)
+		result   = &Result{ErrorModel: new(ErrorModel)}
+	)
+	if opt.Realign && opt.Reference == nil {
+		return nil, fmt.Errorf("realignment requires the reference")
+	}
+	if opt.Primers != nil {
+		opt.Primers = opt.Primers.fresh()
+	}
+	for i, marker := range t.Markers {
+		counters[i] = newCounter(marker, &opt)
+		if counters[i] == nil {
+			return nil, fmt.Errorf("%s: unknown marker type %T", marker.GetID(), marker)
+		}
+		byChrom[marker.GetCHROM()] = append(byChrom[marker.GetCHROM()], counters[i])
+	}
+
+	var (
+		scanner = NewScanner(alignments)
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		SAMrecord, err := NewSAM(scanner.Text())
+		if err != nil {
+			err := &ParseError{File: readerName(alignments), Line: line, Record: scanner.Text(), Err: err}
+			if !opt.Lenient {
+				return nil, err
+			}
+			result.Rejected = append(result.Rejected, err)
+			continue
+		}
+		if SAMrecord == nil {
+			continue
+		}
+		if opt.Primers != nil && !opt.Primers.Apply(SAMrecord, opt.PrimerTolerance, opt.DropOffTarget) {
+			continue
+		}
+		result.ErrorModel.count(SAMrecord, sites, &opt)
+		for _, counter := range byChrom[SAMrecord.chr] {
+			counter.count(SAMrecord)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := opt.Reference.Err(); err != nil {
+		return nil, err
+	}
+
+	// Genotypes are called after all reads, with the error model of the whole sample.
+	// Long reads are always called against the error model, with at least the error rate of long reads.
+	if opt.ErrorCall {
+		opt.errorModel = result.ErrorModel
+	}
+	if opt.LongReads {
+		model := *result.ErrorModel
+		model.floor = longReadErrorRate
+		opt.errorModel = &model
+	}
+	for _, counter := range counters {
+		result.Markers = append(result.Markers, counter.typed())
+	}
+	result.Primers = opt.Primers
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package typing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTyper(t *testing.T) {
+	markers, err := NewVCFFormat(strings.NewReader("#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n" +
+		"chr1\t5\ts1\tA\tG\t.\tPASS\t.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		typer = NewTyper(markers, DefaultOptions())
+		reads = func(bases ...string) string {
+			var s strings.Builder
+			for i, base := range bases {
+				s.WriteString("r" + string(rune('0'+i)) + "\t0\tchr1\t1\t60\t8M\t*\t0\t0\tACGT" + base + "CAG\tIIIIIIII\n")
+			}
+			return s.String()
+		}
+		cases = []struct {
+			sam  string
+			want string
+		}{
+			{reads("A", "A", "G", "G"), "s1\tA\tG"},
+			{reads("G", "G", "G"), "s1\tG\tG"}, // the same Typer types another sample.
+		}
+	)
+	for _, c := range cases {
+		result, err := typer.Type(strings.NewReader(c.sam))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.Markers[0].String(); got != c.want {
+			t.Errorf("typed %q, want %q", got, c.want)
+		}
+	}
+
+	if _, err := typer.Type(strings.NewReader("@HD\tVN:1.6\nr0\t0\tchr1\n")); err == nil ||
+		!strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("truncated record gave error %v, want error at line 2", err)
+	}
+}
@@ -0,0 +1,240 @@
+package typing
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type INFOKey string
+type INFOKeyCollection map[INFOKey]string
+
+// InfoKeyCollection
+var _ = INFOKeyCollection{
+	"AF":     "allele frequency",
+	"AN":     "the number of alternative allele",
+	"OFFSET": "a particular field for microhaplotype",
+	"SNPS":   "absolute positions of all SNPs in a microhaplotype, an alternative to OFFSET",
+	"MOTIF":  "repeat unit of a short tandem repeat (STR)",
+	"END":    "last position of the repeat region of an STR",
+	"PLOIDY": "number of copies of the marker, e.g. 1 for haploid loci",
+}
+
+type INFOValue []any
+
+func (v INFOValue) String() string {
+	var newV []string
+	switch v[0].(type) {
+	case float64, float32:
+		for i := range v {
+			newV = append(newV, fmt.Sprintf("%f", v[i]))
+		}
+	case uint64, int64, int32, uint:
+		for i := range v {
+			newV = append(newV, fmt.Sprintf("%d", v[i]))
+		}
+	}
+	return strings.Join(newV, ",")
+}
+
+/*
+VCFFormat struct hold a VCF record. The Position starts from one not zero.
+The format follows as:
+
+	#CHROM	POS	ID	REF	ALT	QUAL FILTER INFO	FORMAT	NA00001
+	1	2827694	rs2376870	CGTGGATGCGGGGAC	C	.	PASS	SVTYPE=DEL;END=2827762;HOMLEN=1;HOMSEQ=G;SVLEN=-68	GT:GQ	1/1:13.9
+	2	321682	.	T	<DEL>	6    PASS	SVTYPE=DEL;END=321887;SVLEN=-205;CIPOS=-56,20;CIEND=-10,62	GT:GQ	0/1:12
+*/
+type VCFFormat struct {
+	CHROM  string
+	POS    int64
+	ID     string
+	REF    string
+	ALT    string
+	QUAL   string
+	FILTER string
+	INFO   map[INFOKey]INFOValue
+
+	// options of the Typer the marker is typed by, or nil for DefaultOptions.
+	options *Options
+}
+
+func (v *VCFFormat) String() string {
+	var properties []string
+
+	for key, value := range v.INFO {
+		properties = append(properties, string(key)+"="+value.String())
+	}
+
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s",
+		v.CHROM, v.POS, v.ID,
+		v.REF, v.ALT,
+		v.QUAL, v.FILTER,
+		strings.Join(properties, ";"))
+}
+
+// parseINFO parse INFO field from a string into a map.
+func parseINFO(s string) map[INFOKey]INFOValue {
+	var INFO = make(map[INFOKey]INFOValue)
+	property := strings.Split(s, ";")
+	if len(property) == 0 {
+		return nil
+	}
+	for _, substring := range property {
+		if index := strings.IndexAny(substring, "="); index != -1 {
+			INFO[INFOKey(substring[0:index])] = parseINFOValue(substring[index+1:])
+		}
+	}
+	return INFO
+}
+
+// parseINFOValue parse INFO value of each property into a slice, whatever one or more values.
+func parseINFOValue(s string) INFOValue {
+	var InfoValue []any
+	if values := strings.Split(s, ","); len(values) == 0 {
+		InfoValue = []any{s}
+	} else {
+		for _, v := range values {
+			InfoValue = append(InfoValue, v)
+		}
+	}
+	return INFOValue(InfoValue)
+}
+
+// NewVCFFormat import SNP site form VCF format file and return a array of SNP. A malformed record is returned as
+// a *ParseError.
+func NewVCFFormat(file io.Reader) ([]GeneticMarker, error) {
+	records, _, err := readVCFFormat(file, false)
+	return records, err
+}
+
+// NewVCFFormatLenient imports markers like NewVCFFormat, but skips malformed records and returns them.
+func NewVCFFormatLenient(file io.Reader) ([]GeneticMarker, []*ParseError, error) {
+	return readVCFFormat(file, true)
+}
+
+func readVCFFormat(file io.Reader, lenient bool) (records []GeneticMarker, rejected []*ParseError, err error) {
+	var (
+		scanner = NewScanner(file)
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		if text := scanner.Text(); text != "" && text[0] != '#' {
+			marker, err := parseVCFRecord(text)
+			if err != nil {
+				err := &ParseError{File: readerName(file), Line: line, Record: text, Err: err}
+				if !lenient {
+					return nil, nil, err
+				}
+				rejected = append(rejected, err)
+				continue
+			}
+			records = append(records, marker)
+		}
+	}
+	return records, rejected, scanner.Err()
+}
+
+// parseVCFRecord returns the marker of a VCF record: a microhaplotype with OFFSET or SNPS, an STR with MOTIF, an
+// indel or MNP, or else a SNP.
+func parseVCFRecord(text string) (GeneticMarker, error) {
+	fields := strings.Split(text, "\t")
+	if len(fields) < 8 {
+		return nil, fmt.Errorf("%d fields, expected at least 8", len(fields))
+	}
+	Pos, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || Pos < 1 {
+		return nil, fmt.Errorf("invalid POS %q", fields[1])
+	}
+	record := VCFFormat{
+		CHROM: fields[0], POS: Pos, ID: fields[2],
+		REF: fields[3], ALT: fields[4],
+		QUAL: fields[5], FILTER: fields[6],
+		INFO: parseINFO(fields[7]),
+	}
+	if v, ok := record.INFO["PLOIDY"]; ok && len(v) > 0 {
+		if n, err := strconv.Atoi(fmt.Sprint(v[0])); err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: invalid PLOIDY %v", record.ID, v[0])
+		}
+	}
+	if v, ok := record.INFO["OFFSET"]; ok {
+		var offset []uint64
+		for i := range v {
+			sub, err := strconv.ParseUint(fmt.Sprint(v[i]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid OFFSET: %w", record.ID, err)
+			}
+			offset = append(offset, sub)
+		}
+		return NewMH(record, offset), nil
+	} else if v, ok := record.INFO["SNPS"]; ok {
+		// Absolute positions of all SNPs, e.g. SNPS=16574710,16574718,16574732, are converted to OFFSET.
+		var positions []int64
+		for i := range v {
+			sub, err := strconv.ParseInt(fmt.Sprint(v[i]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid SNPS: %w", record.ID, err)
+			}
+			positions = append(positions, sub)
+		}
+		// Bases of REF and ALT follow the listed positions, and are reordered with them.
+		order := make([]int, len(positions))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return positions[order[i]] < positions[order[j]] })
+		var offset []uint64
+		for i := 1; i < len(order); i++ {
+			if positions[order[i]] == positions[order[i-1]] {
+				return nil, fmt.Errorf("%s: duplicate SNPS position %d", record.ID, positions[order[i]])
+			}
+			offset = append(offset, uint64(positions[order[i]]-positions[order[0]]))
+		}
+		if len(offset) == 0 {
+			return nil, fmt.Errorf("%s: SNPS has %d position, expected at least 2", record.ID, len(positions))
+		}
+		alleles := strings.Split(record.ALT, ",")
+		for i, allele := range append([]string{record.REF}, alleles...) {
+			if allele == "." {
+				continue
+			}
+			bases := strings.Split(allele, "-")
+			if len(bases) != len(positions) {
+				return nil, fmt.Errorf("%s: allele %s has %d bases, expected %d as SNPS", record.ID, allele, len(bases), len(positions))
+			}
+			sorted := make([]string, len(bases))
+			for j, k := range order {
+				sorted[j] = bases[k]
+			}
+			if i == 0 {
+				record.REF = strings.Join(sorted, "-")
+			} else {
+				alleles[i-1] = strings.Join(sorted, "-")
+			}
+		}
+		record.ALT = strings.Join(alleles, ",")
+		record.POS = positions[order[0]]
+		return NewMH(record, offset), nil
+	} else if _, ok := record.INFO["MOTIF"]; ok {
+		return NewSTR(record)
+	} else if isInDel(record) {
+		return NewInDel(record), nil
+	}
+	return SNP{VCFFormat: record}, nil
+}
+
+type GeneticMarker interface {
+	GetCHROM() string
+	GetPOS() int64
+	GetID() string
+	// AlleleDepth returns the depth of each called allele keyed by its name.
+	AlleleDepth() map[string]float64
+	String() string
+	// VerboseString returns the allele depths behind the call, as a line of the verbose output.
+	VerboseString() string
+	// Ploidy returns the number of copies of the marker in the sample.
+	Ploidy() int
+}
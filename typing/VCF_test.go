@@ -0,0 +1,51 @@
+package typing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAdjustPos(t *testing.T) {
+	var cases = []struct {
+		pos   int64
+		cigar string
+		want  int64
+	}{
+		{80, "100M", 80},
+		{80, "65M2D1I35M", 79},
+		{80, "10M3I90M", 83},
+	}
+	for _, c := range cases {
+		if got := AdjustPos(c.pos, c.cigar); got != c.want {
+			t.Errorf("AdjustPos(%d, %s) = %d, want %d", c.pos, c.cigar, got, c.want)
+		}
+	}
+}
+
+func TestParseSNPS(t *testing.T) {
+	// Positions listed out of order are sorted with the bases of REF and ALT.
+	markers, err := NewVCFFormat(strings.NewReader("chr1\t100\tmh\tA-C-G\tT-C-G,A-G-C\t.\tPASS\tSNPS=130,100,118\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mh := markers[0].(MH)
+	if mh.POS != 100 || fmt.Sprint(mh.OffSet) != "[18 30]" || mh.REF != "C-G-A" || mh.ALT != "C-G-T,G-C-A" {
+		t.Errorf("SNPS parsed to POS %d OFFSET %v REF %s ALT %s, want 100 [18 30] C-G-A C-G-T,G-C-A",
+			mh.POS, mh.OffSet, mh.REF, mh.ALT)
+	}
+
+	for _, record := range []string{
+		"chr1\t100\tmh\tA\tT\t.\tPASS\tSNPS=100",              // one SNP.
+		"chr1\t100\tmh\tA-C\tT-G\t.\tPASS\tSNPS=100,100",      // the same SNP twice.
+		"chr1\t100\tmh\tA-C\tT-G-A\t.\tPASS\tSNPS=100,118",    // an allele of three SNPs.
+		"chr1\t100\tmh\tA-C\tT-G\t.\tPASS\tSNPS=100,chr1:118", // not a position.
+	} {
+		_, err := NewVCFFormat(strings.NewReader("#header\n" + record + "\n"))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) || parseError.Line != 2 {
+			t.Errorf("%q: error %v, want a ParseError at line 2", record, err)
+		}
+	}
+}
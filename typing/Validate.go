@@ -0,0 +1,252 @@
+package typing
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PanelIssue is an inconsistency found in a panel VCF, located by line number.
+type PanelIssue struct {
+	Line    int
+	ID      string
+	Message string
+}
+
+func (issue PanelIssue) String() string {
+	return fmt.Sprintf("line %d\t%s\t%s", issue.Line, issue.ID, issue.Message)
+}
+
+// panelLocus records the span of a microhaplotype for overlap checking.
+type panelLocus struct {
+	line       int
+	id         string
+	chrom      string
+	start, end int64
+}
+
+// checkHaplotype checks that a MH allele consists of n dash-separated bases.
+func checkHaplotype(allele string, n int) string {
+	bases := strings.Split(allele, "-")
+	if len(bases) != n {
+		return fmt.Sprintf("allele %s has %d bases, expected %d (len(OFFSET)+1)", allele, len(bases), n)
+	}
+	for _, base := range bases {
+		if len(base) != 1 || !strings.Contains("ATCG", base) {
+			return fmt.Sprintf("allele %s has invalid base %q", allele, base)
+		}
+	}
+	return ""
+}
+
+// ValidatePanel checks the consistency of a panel VCF, and the REF alleles against reference sequences if given.
+func ValidatePanel(file io.Reader, reference *FASTA) (issues []PanelIssue, err error) {
+	var (
+		lineNumber int
+		ids        = make(map[string]int)
+		loci       []panelLocus
+	)
+	report := func(id, format string, a ...any) {
+		issues = append(issues, PanelIssue{Line: lineNumber, ID: id, Message: fmt.Sprintf(format, a...)})
+	}
+
+	scanner := NewScanner(file)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\t\r ")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 8 {
+			report("", "%d fields, expected at least 8", len(fields))
+			continue
+		}
+		id := fields[2]
+		if previous, ok := ids[id]; ok && id != "." {
+			report(id, "duplicate ID, first defined at line %d", previous)
+		} else {
+			ids[id] = lineNumber
+		}
+		pos, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || pos < 1 {
+			report(id, "invalid POS %q", fields[1])
+			continue
+		}
+
+		var (
+			alleles = append([]string{fields[3]}, strings.Split(fields[4], ",")...)
+			seen    = make(map[string]bool, len(alleles))
+		)
+		for _, allele := range alleles {
+			if seen[allele] {
+				report(id, "duplicate allele %s", allele)
+			}
+			seen[allele] = true
+		}
+
+		info := parseINFO(fields[7])
+		value, ok := info["OFFSET"]
+		if v, isSNPS := info["SNPS"]; !ok && isSNPS {
+			// Absolute positions are sorted as by the loader, with the bases of the alleles, and checked as OFFSET
+			// relative to the first one.
+			var positions []int64
+			for _, v := range v {
+				p, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+				if err != nil {
+					report(id, "invalid SNPS %v", v)
+					break
+				}
+				positions = append(positions, p)
+			}
+			if len(positions) < len(v) {
+				continue
+			}
+			order := make([]int, len(positions))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(i, j int) bool { return positions[order[i]] < positions[order[j]] })
+			ok, value, pos = true, nil, positions[order[0]]
+			var duplicate bool
+			for i := 1; i < len(order); i++ {
+				if positions[order[i]] == positions[order[i-1]] {
+					report(id, "duplicate SNPS position %d", positions[order[i]])
+					duplicate = true
+				}
+				value = append(value, strconv.FormatInt(positions[order[i]]-pos, 10))
+			}
+			if len(value) == 0 {
+				report(id, "SNPS has %d position, expected at least 2", len(positions))
+			}
+			if duplicate || len(value) == 0 {
+				continue
+			}
+			for i, allele := range alleles {
+				if bases := strings.Split(allele, "-"); allele != "." && len(bases) == len(order) {
+					sorted := make([]string, len(bases))
+					for j, k := range order {
+						sorted[j] = bases[k]
+					}
+					alleles[i] = strings.Join(sorted, "-")
+				}
+			}
+		}
+		if motif, isSTR := info["MOTIF"]; isSTR {
+			if len(motif) == 0 || strings.Trim(strings.ToUpper(fmt.Sprint(motif[0])), "ATCG") != "" {
+				report(id, "MOTIF %v has invalid base", motif)
+			}
+			if end, ok := info["END"]; ok {
+				if n, err := strconv.ParseInt(fmt.Sprint(end[0]), 10, 64); err != nil || n < pos {
+					report(id, "END %v is not a position after POS %d", end[0], pos)
+				}
+			} else if fields[3] == "." {
+				report(id, "STR without END or REF")
+			}
+			continue
+		}
+		if !ok {
+			// SNP marker, whose REF may be left as "." to be filled from the reference, as ALT without other allele.
+			for _, allele := range alleles {
+				if allele != "." && strings.Trim(allele, "ATCGN") != "" {
+					report(id, "allele %s has invalid base", allele)
+				}
+			}
+			if reference != nil && reference.Has(fields[0]) {
+				if end := pos - 1 + max(int64(len(fields[3])), 1); end > reference.Index[fields[0]].Length {
+					report(id, "POS %d is beyond the end of %s", pos, fields[0])
+				} else if ref := reference.Fetch(fields[0], pos, end); fields[3] != "." && !strings.EqualFold(ref, fields[3]) {
+					report(id, "REF %s differs from reference %s", fields[3], ref)
+				}
+			} else if reference != nil {
+				report(id, "CHROM %s is not found in reference", fields[0])
+			}
+			continue
+		}
+
+		// Microhaplotype marker.
+		var (
+			offset   []int64
+			previous int64
+			valid    = true
+		)
+		for _, v := range value {
+			n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+			if err != nil {
+				report(id, "invalid OFFSET %v", v)
+				valid = false
+				break
+			}
+			if n <= previous {
+				report(id, "OFFSET %d is not strictly increasing", n)
+				valid = false
+			}
+			offset, previous = append(offset, n), n
+		}
+		if !valid {
+			continue
+		}
+		for _, allele := range alleles {
+			if allele == "." {
+				continue // REF filled from the reference, or no ALT.
+			}
+			if message := checkHaplotype(allele, len(offset)+1); message != "" {
+				report(id, "%s", message)
+			}
+		}
+		loci = append(loci, panelLocus{line: lineNumber, id: id, chrom: fields[0], start: pos, end: pos + offset[len(offset)-1]})
+
+		if reference == nil {
+			continue
+		}
+		if !reference.Has(fields[0]) {
+			report(id, "CHROM %s is not found in reference", fields[0])
+			continue
+		}
+		var bases []string
+		if alleles[0] != "." {
+			bases = strings.Split(alleles[0], "-")
+		}
+		for i, p := range append([]int64{0}, offset...) {
+			if pos+p > reference.Index[fields[0]].Length {
+				report(id, "SNP at %d is beyond the end of %s", pos+p, fields[0])
+				break
+			}
+			if base := reference.Base(fields[0], pos+p); i < len(bases) && !strings.EqualFold(bases[i], base) {
+				report(id, "REF base %s at %d (OFFSET %d) differs from reference %s", bases[i], pos+p, p, base)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(loci, func(i, j int) bool {
+		if loci[i].chrom == loci[j].chrom {
+			return loci[i].start < loci[j].start
+		}
+		return loci[i].chrom < loci[j].chrom
+	})
+	// Each locus is compared with the one reaching furthest before it on the chromosome, so that loci nested in a
+	// long one are all reported.
+	var furthest int
+	for i := 1; i < len(loci); i++ {
+		if loci[i].chrom != loci[furthest].chrom {
+			furthest = i
+			continue
+		}
+		if loci[i].start <= loci[furthest].end {
+			lineNumber = loci[i].line
+			report(loci[i].id, "overlaps %s (line %d)", loci[furthest].id, loci[furthest].line)
+		}
+		if loci[i].end > loci[furthest].end {
+			furthest = i
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, reference.Err()
+}
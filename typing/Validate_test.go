@@ -0,0 +1,82 @@
+package typing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidatePanel(t *testing.T) {
+	const panel = "##fileformat=VCFv4.2\n" +
+		"chr1\t100\tA\tC-T\tG-A\t.\tPASS\tOFFSET=100\n" +
+		"chr1\t110\tB\tC-T\tG-A\t.\tPASS\tOFFSET=10\n" + // nested in A.
+		"chr1\t150\tC\tC-T\tG-A\t.\tPASS\tOFFSET=10\n" + // nested in A, after B.
+		"chr1\t300\tA\tC-T\tG\t.\tPASS\tOFFSET=5\n" +
+		"chr1\t400\tE\tC-T-A\t.\t.\tPASS\tOFFSET=5,3\n" +
+		"chr2\t150\tF\tC-T\tG-A\t.\tPASS\tOFFSET=10\n" +
+		"chr2\t300\tG\t.\tC-T\t.\tPASS\tOFFSET=10\n" + // REF filled from the reference.
+		"chr2\t400\tH\tA-C-G\tT-C-G\t.\tPASS\tSNPS=420,400,410\n" + // sorted with the bases by the loader.
+		"chr2\t500\tsnp\tA\t.\t.\tPASS\t.\n" +
+		"chr2\t600\tI\tA-C\tG-C\t.\tPASS\tSNPS=600,600\n"
+	issues, err := ValidatePanel(strings.NewReader(panel), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []string{
+		"line 3\tB\toverlaps A (line 2)",
+		"line 4\tC\toverlaps A (line 2)",
+		"line 5\tA\tduplicate ID, first defined at line 2",
+		"line 5\tA\tallele G has 1 bases, expected 2 (len(OFFSET)+1)",
+		"line 6\tE\tOFFSET 3 is not strictly increasing",
+		"line 11\tI\tduplicate SNPS position 600",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues %v, want %d", issues, len(want))
+	}
+	for i := range want {
+		if got := issues[i].String(); got != want[i] {
+			t.Errorf("issue %d %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestValidatePanelReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ref.fa")
+	if err := os.WriteFile(path, []byte(">chr1\nACGTACGTAC\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handle, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+	reference, err := NewFASTA(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const panel = "chr1\t2\tok\tC-T\tA-A\t.\tPASS\tOFFSET=2\n" +
+		"chr1\t6\tmh\tG-G\tA-A\t.\tPASS\tOFFSET=1\n" +
+		"chr1\t3\tsnp\tA\tG\t.\tPASS\t.\n" +
+		"chrX\t3\tmissing\tA-A\tG-G\t.\tPASS\tOFFSET=1\n" +
+		"chr1\t9\tsnps\tC-A\tG-G\t.\tPASS\tSNPS=10,9\n" + // REF A-C once sorted.
+		"chr1\t1\tdot\t.\tG\t.\tPASS\t.\n"
+	issues, err := ValidatePanel(strings.NewReader(panel), reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = []string{
+		"line 2\tmh\tREF base G at 6 (OFFSET 0) differs from reference C",
+		"line 3\tsnp\tREF A differs from reference G",
+		"line 4\tmissing\tCHROM chrX is not found in reference",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues %v, want %d", issues, len(want))
+	}
+	for i := range want {
+		if got := issues[i].String(); got != want[i] {
+			t.Errorf("issue %d %q, want %q", i, got, want[i])
+		}
+	}
+}